@@ -2,35 +2,114 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	pb "client/pokemon"
+	"shared/config"
+	"shared/logging"
+	"shared/tlsconfig"
+	"shared/tracing"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/proto"
 )
 
 func main() {
+	url := flag.String("url", "ws://localhost:8080/ws", "websocket url of the server, wss:// connects with TLS")
+	token := flag.String("token", "", "bearer token sent to the server when it requires authentication")
+	caFile := flag.String("ca", "", "path to the PEM CA certificates that verify a wss:// server instead of the system roots")
+	insecure := flag.Bool("insecure", false, "don't verify the certificate of a wss:// server, only for development")
+	certFile := flag.String("cert", "", "path to the PEM client certificate for a server that requires mutual TLS")
+	keyFile := flag.String("key", "", "path to the PEM private key of the client certificate")
+	readBufferSize := flag.Int("read-buffer-size", 0, "size in bytes of the websocket read buffer, 0 uses the library default")
+	writeBufferSize := flag.Int("write-buffer-size", 0, "size in bytes of the websocket write buffer, 0 uses the library default")
+	traceExporter := flag.String("trace-exporter", tracing.NoExporter, "exporter of the query spans, none, stdout or file")
+	traceFile := flag.String("trace-file", tracing.DefaultFile, "file the spans are appended to with the file exporter")
+	logLevel := flag.String("log-level", "info", "lowest level of the logged lines, debug, info, warn or error")
+	logFormat := flag.String("log-format", logging.TextFormat, "format of the logs, text or json")
+	flag.String(config.ConfigFlag, "", "path to a JSON config file of flag values, relative paths in it are relative to its directory and environment variables and flags override it")
+	printConfig := flag.Bool(config.PrintConfigFlag, false, "print the effective settings and where they come from and exit")
+	flag.Parse()
+
+	// Fill the flags that weren't given from the environment and the config
+	// file, the token is masked when the settings are printed
+	sources, err := config.Load(flag.CommandLine, "POKEMON_CLIENT_", "ca", "cert", "key", "trace-file")
+	if err != nil {
+		slog.Error("Config error", "error", err)
+		return
+	}
+
+	if *printConfig {
+		config.Print(flag.CommandLine, sources, "token")
+		return
+	}
+
+	// Write structured logs to stderr in the configured format, the lines are
+	// tagged with the url of the server
+	if err := logging.Setup(os.Stderr, *logLevel, *logFormat); err != nil {
+		slog.Error("Config error", "error", err)
+		return
+	}
+	slog.SetDefault(slog.Default().With("url", *url))
+
+	// Export the spans of the queries, the server continues their traces
+	shutdownTracing, err := tracing.Setup("pokemon-client", *traceExporter, *traceFile)
+	if err != nil {
+		slog.Error("Tracing config error", "error", err)
+		return
+	}
+	defer shutdownTracing()
+
 	// Create a new reader to read user input
 	reader := bufio.NewReader(os.Stdin)
 
-	// Create a new WebSocket dialer
-	dialer := websocket.DefaultDialer
+	// Create a new WebSocket dialer with the TLS config for wss:// urls
+	tlsConfig, err := tlsconfig.NewClient(*caFile, *certFile, *keyFile, *insecure)
+	if err != nil {
+		slog.Error("TLS config error", "error", err)
+		return
+	}
+
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = tlsConfig
+	dialer.ReadBufferSize = *readBufferSize
+	dialer.WriteBufferSize = *writeBufferSize
 
 	// Dial the WebSocket server
-	conn, _, err := dialer.Dial("ws://localhost:8080/ws", nil)
+	var header = http.Header{}
+	if *token != "" {
+		header.Set("Authorization", "Bearer "+*token)
+	}
+
+	conn, response, err := dialer.Dial(*url, header)
 	if err != nil {
-		log.Println("WebSocket dial error:", err)
+		if response != nil && response.StatusCode == http.StatusUnauthorized {
+			slog.Error("WebSocket dial error, the server rejected the token, use -token")
+			return
+		}
+
+		slog.Error("WebSocket dial error", "error", err)
 		return
 	}
 
-	// Read messages from the server
-	go readMessages(conn)
+	// Read messages from the server, the spans of the queries end when their
+	// response is read
+	pending := &PendingSpans{}
+	go readMessages(conn, pending)
+
+	tracer := otel.Tracer(tracing.TracerName)
 
 	for {
 		// Sleep for a second to prevent spamming
@@ -76,35 +155,63 @@ func main() {
 			fmt.Println("Invalid command")
 		}
 
+		if queryCommand == nil {
+			continue
+		}
+
+		// Start the span of the query and send its trace context along
+		ctx, span := tracer.Start(context.Background(), "PokemonQuery",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(tracing.QueryAttributes(queryCommand)...),
+		)
+		queryCommand.Traceparent = tracing.InjectTraceparent(ctx)
+
+		_, encodeSpan := tracer.Start(ctx, "encode")
 		query, error := proto.Marshal(queryCommand)
+		encodeSpan.End()
 		if error != nil {
-			log.Println("protobuf encode error:", error)
+			slog.Error("Protobuf encode error", "error", error)
+			span.RecordError(error)
+			span.SetStatus(codes.Error, "encode failed")
+			span.End()
 			continue
 		}
 
+		slog.Debug("Sending query", "trace_id", span.SpanContext().TraceID().String())
+		pending.push(span)
 		sendMessage(conn, query)
 	}
 }
 
-// Read messages from the WebSocket connection
-func readMessages(conn *websocket.Conn) {
+// Read messages from the WebSocket connection, each response ends the span
+// of the oldest pending query
+func readMessages(conn *websocket.Conn, pending *PendingSpans) {
+	tracer := otel.Tracer(tracing.TracerName)
+
 	for {
 		messageType, message, err := conn.ReadMessage()
 		if err != nil {
-			log.Println("WebSocket read error:", err)
+			slog.Error("WebSocket read error", "error", err)
 			return
 		}
 
 		switch messageType {
 		case websocket.TextMessage:
-			log.Println(string(message))
+			fmt.Println(string(message))
 
 		case websocket.BinaryMessage:
+			span := pending.pop()
+
 			// Decode the message as a WebSocketMessage
+			_, decodeSpan := tracer.Start(trace.ContextWithSpan(context.Background(), span), "decode")
 			newWebSocketMessage := &pb.WebSocketMessage{}
 			err = proto.Unmarshal(message, newWebSocketMessage)
+			decodeSpan.End()
 			if err != nil {
-				log.Println("protobuf decode error:", err)
+				slog.Error("Protobuf decode error", "error", err)
+				span.RecordError(err)
+				span.SetStatus(codes.Error, "decode failed")
+				span.End()
 				continue
 			}
 
@@ -114,16 +221,20 @@ func readMessages(conn *websocket.Conn) {
 				for _, p := range newWebSocketMessage.GetPokemonList().Pokemon {
 					fmt.Printf("Name: %s, id: %s, type: %s\n", p.Name, p.Id, p.Type)
 				}
+				span.SetAttributes(attribute.Int("pokemon.results", len(newWebSocketMessage.GetPokemonList().Pokemon)))
 
 			case *pb.WebSocketMessage_ErrorMessage:
-				fmt.Println("[SERVER]:", newWebSocketMessage.GetErrorMessage().ErrorMessage)
+				errorMessage := newWebSocketMessage.GetErrorMessage()
+				fmt.Printf("[SERVER]: query failed (%s): %s\n", errorMessage.ErrorCode, errorMessage.ErrorMessage)
+				span.SetStatus(codes.Error, errorMessage.ErrorMessage)
 
 			default:
 				fmt.Println("undefined message type")
 			}
+			span.End()
 
 		default:
-			log.Println("Received unsupported message type")
+			slog.Warn("Received unsupported message type", "type", messageType)
 		}
 	}
 }
@@ -132,6 +243,6 @@ func readMessages(conn *websocket.Conn) {
 func sendMessage(conn *websocket.Conn, msg []byte) {
 	err := conn.WriteMessage(websocket.BinaryMessage, msg)
 	if err != nil {
-		log.Println("WebSocket write error:", err)
+		slog.Error("WebSocket write error", "error", err)
 	}
 }
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PendingSpans are the spans of the queries waiting for their response, the
+// server answers the queries of a connection in order so the oldest span is
+// the one of the next response. It is shared between the input loop and the
+// read goroutine
+type PendingSpans struct {
+	mutex sync.Mutex
+	spans []trace.Span
+}
+
+// Add the span of a query that was sent
+func (pending *PendingSpans) push(span trace.Span) {
+	pending.mutex.Lock()
+	defer pending.mutex.Unlock()
+
+	pending.spans = append(pending.spans, span)
+}
+
+// Get and forget the span of the oldest query, a span that records nothing
+// is returned if no query is waiting
+func (pending *PendingSpans) pop() trace.Span {
+	pending.mutex.Lock()
+	defer pending.mutex.Unlock()
+
+	if len(pending.spans) == 0 {
+		return trace.SpanFromContext(context.Background())
+	}
+
+	span := pending.spans[0]
+	pending.spans = pending.spans[1:]
+
+	return span
+}
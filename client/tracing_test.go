@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestPendingSpansPopsInOrder(t *testing.T) {
+	tracer := sdktrace.NewTracerProvider().Tracer("test")
+
+	var pending PendingSpans
+	var spans []trace.Span
+	for _, name := range []string{"first", "second", "third"} {
+		_, span := tracer.Start(context.Background(), name)
+		spans = append(spans, span)
+		pending.push(span)
+	}
+
+	// Each response ends the span of the oldest query still waiting
+	for i, want := range spans {
+		if got := pending.pop(); got.SpanContext().SpanID() != want.SpanContext().SpanID() {
+			t.Errorf("pop %d returned span %s, want %s", i, got.SpanContext().SpanID(), want.SpanContext().SpanID())
+		}
+	}
+
+	// A response without a pending query gets a span that records nothing
+	if span := pending.pop(); span.IsRecording() || span.SpanContext().IsValid() {
+		t.Errorf("pop with no pending query returned a recording span")
+	}
+}
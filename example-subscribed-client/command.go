@@ -0,0 +1,326 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "subscribed-client/protobuf"
+)
+
+// The commands the user can type, a request is sent to the server for
+// all of them except exit
+const (
+	subscribeCommand     = "subs"
+	unsubscribeCommand   = "unsubs"
+	channelsCommand      = "channels"
+	subscriptionsCommand = "subscriptions"
+	publishCommand       = "pub"
+	exitCommand          = "exit"
+)
+
+// The flag of the publish command that stops the server sending the message back to us
+const noEchoFlag = "-noecho"
+
+// The flags of the subscribe command that ask the server to replay the channel
+// history, -since takes a duration like 30s or a RFC 3339 time
+const (
+	lastFlag     = "-last"
+	sequenceFlag = "-seq"
+	sinceFlag    = "-since"
+)
+
+// The flags of the subscribe command that ask the server to send at most one
+// update per interval, only the latest tick of each channel is sent unless
+// -batch asks for all of them in one message
+const (
+	throttleFlag = "-throttle"
+	batchFlag    = "-batch"
+)
+
+// The flag of the subscribe command after the channels, the rest of the line is
+// a filter like "value > 50" the server applies before sending a tick
+const whereFlag = "-where"
+
+// A channel is one or more dot separated words of letters, digits, '-' and '_',
+// a word may be the wildcard '*' and the last word may be the wildcard '>'
+var channelNamePattern = regexp.MustCompile(`^(([A-Za-z0-9_-]+|\*)(\.([A-Za-z0-9_-]+|\*))*(\.>)?|>)$`)
+
+// Messages can only be published to a channel name without wildcards
+var publishChannelPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`)
+
+// The id of the last request sent to the server, echoed back in the server's response
+var lastRequestID atomic.Int64
+
+// Get the id for the next request sent to the server
+func nextRequestID() string {
+	return strconv.FormatInt(lastRequestID.Add(1), 10)
+}
+
+// Command is a parsed line of user input
+type Command struct {
+	name     string
+	channels []string
+	payload  string
+	noEcho   bool
+	replay   *pb.ReplayOptions
+	throttle *pb.ThrottleOptions
+	filter   string
+}
+
+// Parse a line of user input, an error is returned for anything that should
+// not be sent to the server
+func parseCommand(line string) (*Command, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, errors.New("empty command")
+	}
+
+	var command = &Command{name: fields[0]}
+	var args = fields[1:]
+
+	switch command.name {
+	case subscribeCommand, unsubscribeCommand:
+		if command.name == subscribeCommand {
+			rest, err := command.parseSubscribeFlags(args)
+			if err != nil {
+				return nil, err
+			}
+
+			args = rest
+
+			for i, arg := range args {
+				if arg == whereFlag {
+					command.filter = strings.Join(args[i+1:], " ")
+					if command.filter == "" {
+						return nil, fmt.Errorf("%s needs a filter", whereFlag)
+					}
+
+					args = args[:i]
+					break
+				}
+			}
+		}
+
+		if len(args) == 0 {
+			if command.name == subscribeCommand {
+				return nil, fmt.Errorf("usage: %s [%s | %s <sequence> | %s <duration|time>] [%s <interval> [%s]] <channel> [channel...] [%s <filter>]", command.name, lastFlag, sequenceFlag, sinceFlag, throttleFlag, batchFlag, whereFlag)
+			}
+			return nil, fmt.Errorf("usage: %s <channel> [channel...]", command.name)
+		}
+
+		seen := make(map[string]bool)
+		for _, channel := range args {
+			// The flags go before the channels and unsubscribe has none
+			if strings.HasPrefix(channel, "-") {
+				return nil, fmt.Errorf("unknown flag %q", channel)
+			}
+
+			if !channelNamePattern.MatchString(channel) {
+				return nil, fmt.Errorf("invalid channel name %q", channel)
+			}
+
+			if !seen[channel] {
+				seen[channel] = true
+				command.channels = append(command.channels, channel)
+			}
+		}
+
+	case publishCommand:
+		if len(args) > 0 && args[0] == noEchoFlag {
+			command.noEcho = true
+			args = args[1:]
+		}
+
+		if len(args) < 2 {
+			return nil, fmt.Errorf("usage: %s [%s] <channel> <message>", command.name, noEchoFlag)
+		}
+
+		if !publishChannelPattern.MatchString(args[0]) {
+			return nil, fmt.Errorf("invalid channel name %q", args[0])
+		}
+
+		command.channels = []string{args[0]}
+		command.payload = strings.Join(args[1:], " ")
+
+	case channelsCommand, subscriptionsCommand, exitCommand:
+		if len(args) != 0 {
+			return nil, fmt.Errorf("usage: %s", command.name)
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown command %q", command.name)
+	}
+
+	return command, nil
+}
+
+// Parse the optional flags at the start of the subscribe arguments and return
+// the arguments after them
+func (command *Command) parseSubscribeFlags(args []string) ([]string, error) {
+	var batch bool
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		var err error
+		switch args[0] {
+		case lastFlag, sequenceFlag, sinceFlag:
+			if command.replay != nil {
+				return nil, errors.New("only one replay flag can be given")
+			}
+			command.replay, args, err = parseReplayFlag(args)
+
+		case throttleFlag:
+			if len(args) < 2 {
+				return nil, fmt.Errorf("%s needs an interval", throttleFlag)
+			}
+
+			interval, parseErr := time.ParseDuration(args[1])
+			if parseErr != nil || interval < time.Millisecond {
+				return nil, fmt.Errorf("invalid interval %q", args[1])
+			}
+
+			command.throttle = &pb.ThrottleOptions{MinIntervalMs: uint32(interval / time.Millisecond), Mode: pb.ConflationMode_LATEST}
+			args = args[2:]
+
+		case batchFlag:
+			batch = true
+			args = args[1:]
+
+		default:
+			return nil, fmt.Errorf("unknown flag %q", args[0])
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if batch {
+		if command.throttle == nil {
+			return nil, fmt.Errorf("%s needs %s", batchFlag, throttleFlag)
+		}
+		command.throttle.Mode = pb.ConflationMode_BATCH
+	}
+
+	return args, nil
+}
+
+// Parse the replay flag at the start of the subscribe arguments and return
+// the arguments after it
+func parseReplayFlag(args []string) (*pb.ReplayOptions, []string, error) {
+	switch args[0] {
+	case lastFlag:
+		return &pb.ReplayOptions{From: &pb.ReplayOptions_LastValue{LastValue: true}}, args[1:], nil
+
+	case sequenceFlag:
+		if len(args) < 2 {
+			return nil, nil, fmt.Errorf("%s needs a sequence number", sequenceFlag)
+		}
+
+		sequence, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil || sequence == 0 {
+			return nil, nil, fmt.Errorf("invalid sequence number %q", args[1])
+		}
+
+		return &pb.ReplayOptions{From: &pb.ReplayOptions_FromSequence{FromSequence: sequence}}, args[2:], nil
+
+	case sinceFlag:
+		if len(args) < 2 {
+			return nil, nil, fmt.Errorf("%s needs a duration or a time", sinceFlag)
+		}
+
+		var since time.Time
+		if duration, err := time.ParseDuration(args[1]); err == nil && duration > 0 {
+			since = time.Now().Add(-duration)
+		} else if since, err = time.Parse(time.RFC3339, args[1]); err != nil {
+			return nil, nil, fmt.Errorf("invalid duration or time %q", args[1])
+		}
+
+		return &pb.ReplayOptions{From: &pb.ReplayOptions_FromTimestamp{FromTimestamp: since.UnixNano()}}, args[2:], nil
+
+	default:
+		return nil, args, nil
+	}
+}
+
+// Build the message that is sent to the server for the command
+func (command *Command) clientMessage(requestID string) *pb.ClientMessage {
+	switch command.name {
+	case channelsCommand:
+		return &pb.ClientMessage{
+			Payload: &pb.ClientMessage_ListChannelsRequest{
+				ListChannelsRequest: &pb.ListChannelsRequest{RequestId: requestID},
+			},
+		}
+
+	case publishCommand:
+		return &pb.ClientMessage{
+			Payload: &pb.ClientMessage_PublishRequest{
+				PublishRequest: &pb.PublishRequest{
+					RequestId: requestID,
+					Channel:   command.channels[0],
+					Payload:   command.payload,
+					NoEcho:    command.noEcho,
+				},
+			},
+		}
+	}
+
+	var request = &pb.SubscriptionRequest{
+		RequestId: requestID,
+		Channels:  command.channels,
+		Replay:    command.replay,
+		Throttle:  command.throttle,
+		Filter:    command.filter,
+	}
+
+	switch command.name {
+	case subscribeCommand:
+		request.Action = pb.SubscriptionAction_SUBSCRIBE
+	case unsubscribeCommand:
+		request.Action = pb.SubscriptionAction_UNSUBSCRIBE
+	default:
+		request.Action = pb.SubscriptionAction_LIST
+	}
+
+	return &pb.ClientMessage{
+		Payload: &pb.ClientMessage_SubscriptionRequest{SubscriptionRequest: request},
+	}
+}
+
+// PendingRequests keeps the commands that are waiting for the server's ack,
+// it is shared between the input loop and the read goroutine
+type PendingRequests struct {
+	mutex    sync.Mutex
+	commands map[string]*Command
+}
+
+// Create a new empty set of pending requests
+func newPendingRequests() *PendingRequests {
+	return &PendingRequests{
+		commands: make(map[string]*Command),
+	}
+}
+
+// Remember the command sent with the request id
+func (pending *PendingRequests) add(requestID string, command *Command) {
+	pending.mutex.Lock()
+	defer pending.mutex.Unlock()
+
+	pending.commands[requestID] = command
+}
+
+// Get and forget the command sent with the request id
+func (pending *PendingRequests) take(requestID string) *Command {
+	pending.mutex.Lock()
+	defer pending.mutex.Unlock()
+
+	command := pending.commands[requestID]
+	delete(pending.commands, requestID)
+
+	return command
+}
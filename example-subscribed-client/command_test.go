@@ -0,0 +1,166 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "subscribed-client/protobuf"
+)
+
+// Wrap the subscription request in a client message
+func subscriptionMessage(request *pb.SubscriptionRequest) *pb.ClientMessage {
+	request.RequestId = "1"
+	return &pb.ClientMessage{Payload: &pb.ClientMessage_SubscriptionRequest{SubscriptionRequest: request}}
+}
+
+func TestParseCommand(t *testing.T) {
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		line string
+		want *pb.ClientMessage
+	}{
+		{
+			line: "subs prices.btc prices.> prices.btc",
+			want: subscriptionMessage(&pb.SubscriptionRequest{Action: pb.SubscriptionAction_SUBSCRIBE, Channels: []string{"prices.btc", "prices.>"}}),
+		},
+		{
+			line: "subs -last prices.*",
+			want: subscriptionMessage(&pb.SubscriptionRequest{
+				Action:   pb.SubscriptionAction_SUBSCRIBE,
+				Channels: []string{"prices.*"},
+				Replay:   &pb.ReplayOptions{From: &pb.ReplayOptions_LastValue{LastValue: true}},
+			}),
+		},
+		{
+			line: "subs -seq 42 btc",
+			want: subscriptionMessage(&pb.SubscriptionRequest{
+				Action:   pb.SubscriptionAction_SUBSCRIBE,
+				Channels: []string{"btc"},
+				Replay:   &pb.ReplayOptions{From: &pb.ReplayOptions_FromSequence{FromSequence: 42}},
+			}),
+		},
+		{
+			line: "subs -since " + since.Format(time.RFC3339) + " btc",
+			want: subscriptionMessage(&pb.SubscriptionRequest{
+				Action:   pb.SubscriptionAction_SUBSCRIBE,
+				Channels: []string{"btc"},
+				Replay:   &pb.ReplayOptions{From: &pb.ReplayOptions_FromTimestamp{FromTimestamp: since.UnixNano()}},
+			}),
+		},
+		{
+			line: "subs -throttle 250ms btc",
+			want: subscriptionMessage(&pb.SubscriptionRequest{
+				Action:   pb.SubscriptionAction_SUBSCRIBE,
+				Channels: []string{"btc"},
+				Throttle: &pb.ThrottleOptions{MinIntervalMs: 250, Mode: pb.ConflationMode_LATEST},
+			}),
+		},
+		{
+			line: "subs -batch -throttle 1s -seq 7 btc eth",
+			want: subscriptionMessage(&pb.SubscriptionRequest{
+				Action:   pb.SubscriptionAction_SUBSCRIBE,
+				Channels: []string{"btc", "eth"},
+				Replay:   &pb.ReplayOptions{From: &pb.ReplayOptions_FromSequence{FromSequence: 7}},
+				Throttle: &pb.ThrottleOptions{MinIntervalMs: 1000, Mode: pb.ConflationMode_BATCH},
+			}),
+		},
+		{
+			line: "subs prices.> -where value > 50 and source = \"random\"",
+			want: subscriptionMessage(&pb.SubscriptionRequest{
+				Action:   pb.SubscriptionAction_SUBSCRIBE,
+				Channels: []string{"prices.>"},
+				Filter:   `value > 50 and source = "random"`,
+			}),
+		},
+		{
+			line: "unsubs btc eth",
+			want: subscriptionMessage(&pb.SubscriptionRequest{Action: pb.SubscriptionAction_UNSUBSCRIBE, Channels: []string{"btc", "eth"}}),
+		},
+		{
+			line: "subscriptions",
+			want: subscriptionMessage(&pb.SubscriptionRequest{Action: pb.SubscriptionAction_LIST}),
+		},
+		{
+			line: "channels",
+			want: &pb.ClientMessage{Payload: &pb.ClientMessage_ListChannelsRequest{ListChannelsRequest: &pb.ListChannelsRequest{RequestId: "1"}}},
+		},
+		{
+			line: "pub chat hello   world",
+			want: &pb.ClientMessage{Payload: &pb.ClientMessage_PublishRequest{PublishRequest: &pb.PublishRequest{RequestId: "1", Channel: "chat", Payload: "hello world"}}},
+		},
+		{
+			line: "pub -noecho chat hi",
+			want: &pb.ClientMessage{Payload: &pb.ClientMessage_PublishRequest{PublishRequest: &pb.PublishRequest{RequestId: "1", Channel: "chat", Payload: "hi", NoEcho: true}}},
+		},
+	}
+
+	for _, test := range tests {
+		command, err := parseCommand(test.line)
+		if err != nil {
+			t.Errorf("parseCommand(%q): %v", test.line, err)
+			continue
+		}
+
+		if got := command.clientMessage("1"); !proto.Equal(got, test.want) {
+			t.Errorf("parseCommand(%q) sends %v, want %v", test.line, got, test.want)
+		}
+	}
+}
+
+func TestParseCommandSinceDuration(t *testing.T) {
+	before := time.Now()
+	command, err := parseCommand("subs -since 30s btc")
+	if err != nil {
+		t.Fatalf("parseCommand: %v", err)
+	}
+
+	// The replay starts 30s before the command was parsed
+	timestamp := command.replay.GetFromTimestamp()
+	if timestamp < before.Add(-30*time.Second).UnixNano() || timestamp > time.Now().Add(-30*time.Second).UnixNano() {
+		t.Errorf("replay from %v, want 30s before %v", time.Unix(0, timestamp), before)
+	}
+}
+
+func TestParseCommandErrors(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"   ",
+		"subscribe btc",
+		"subs",
+		"subs -batch x",
+		"subs -batch",
+		"subs x -where",
+		"subs -where value > 1",
+		"subs -throttle",
+		"subs -throttle fast btc",
+		"subs -throttle 100us btc",
+		"subs -seq btc",
+		"subs -seq 0 btc",
+		"subs -seq -1 btc",
+		"subs -since btc",
+		"subs -since yesterday btc",
+		"subs -last -seq 5 btc",
+		"subs -verbose btc",
+		"subs x -last",
+		"subs prices..btc",
+		"subs prices.>.btc",
+		"subs prices.b*",
+		"unsubs",
+		"unsubs -last x",
+		"unsubs btc -where value > 1",
+		"pub x",
+		"pub -noecho x",
+		"pub prices.* hi",
+		"pub > hi",
+		"channels extra",
+		"subscriptions btc",
+		"exit now",
+	} {
+		if command, err := parseCommand(line); err == nil {
+			t.Errorf("parseCommand(%q) = %+v, want an error", line, command)
+		}
+	}
+}
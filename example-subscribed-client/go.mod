@@ -1,8 +1,11 @@
 module subscribed-client
 
-go 1.19
+go 1.21
 
 require (
 	github.com/gorilla/websocket v1.5.0
 	google.golang.org/protobuf v1.28.1
+	shared v0.0.0
 )
+
+replace shared => ../shared
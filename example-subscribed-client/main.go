@@ -2,12 +2,18 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
+	"shared/config"
+	"shared/logging"
+	"shared/tlsconfig"
 	pb "subscribed-client/protobuf"
 
 	"github.com/gorilla/websocket"
@@ -15,70 +21,145 @@ import (
 )
 
 func main() {
+	url := flag.String("url", "ws://localhost:8080/ws", "websocket url of the server, wss:// connects with TLS")
+	token := flag.String("token", "", "bearer token sent to the server when it requires authentication")
+	caFile := flag.String("ca", "", "path to the PEM CA certificates that verify a wss:// server instead of the system roots")
+	insecure := flag.Bool("insecure", false, "don't verify the certificate of a wss:// server, only for development")
+	certFile := flag.String("cert", "", "path to the PEM client certificate for a server that requires mutual TLS")
+	keyFile := flag.String("key", "", "path to the PEM private key of the client certificate")
+	readBufferSize := flag.Int("read-buffer-size", 0, "size in bytes of the websocket read buffer, 0 uses the library default")
+	writeBufferSize := flag.Int("write-buffer-size", 0, "size in bytes of the websocket write buffer, 0 uses the library default")
+	logLevel := flag.String("log-level", "info", "lowest level of the logged lines, debug, info, warn or error")
+	logFormat := flag.String("log-format", logging.TextFormat, "format of the logs, text or json")
+	flag.String(config.ConfigFlag, "", "path to a JSON config file of flag values, relative paths in it are relative to its directory and environment variables and flags override it")
+	printConfig := flag.Bool(config.PrintConfigFlag, false, "print the effective settings and where they come from and exit")
+	flag.Parse()
+
+	// Fill the flags that weren't given from the environment and the config
+	// file, the token is masked when the settings are printed
+	sources, err := config.Load(flag.CommandLine, "SUBSCRIBED_CLIENT_", "ca", "cert", "key")
+	if err != nil {
+		slog.Error("Config error", "error", err)
+		return
+	}
+
+	if *printConfig {
+		config.Print(flag.CommandLine, sources, "token")
+		return
+	}
+
+	// Write structured logs to stderr in the configured format, the lines are
+	// tagged with the url of the server
+	if err := logging.Setup(os.Stderr, *logLevel, *logFormat); err != nil {
+		slog.Error("Config error", "error", err)
+		return
+	}
+	slog.SetDefault(slog.Default().With("url", *url))
+
 	// Create a new reader to read user input
 	reader := bufio.NewReader(os.Stdin)
 
-	// Create a new WebSocket dialer
-	dialer := websocket.DefaultDialer
+	// Create a new WebSocket dialer with the TLS config for wss:// urls
+	tlsConfig, err := tlsconfig.NewClient(*caFile, *certFile, *keyFile, *insecure)
+	if err != nil {
+		slog.Error("TLS config error", "error", err)
+		return
+	}
+
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = tlsConfig
+	dialer.ReadBufferSize = *readBufferSize
+	dialer.WriteBufferSize = *writeBufferSize
 
 	// Copy this to the terminal to test the client
-	// subs positive
-	// subs negative
+	// subs positive negative
 	// unsubs positive
-	// unsubs negative
+	// subs prices.crypto.*
+	// subs prices.>
+	// pub chat hello world
+	// subs -last positive
+	// subs -since 30s negative
+	// subs -throttle 5s positive
+	// subs -throttle 5s -batch negative
+	// subs positive negative -where abs(value) < 20
+	// subs prices.> -where metadata.venue == "coinbase" && value > 30000
+	// subscriptions
+	// channels
 
 	// Dial the WebSocket server
-	conn, _, err := dialer.Dial("ws://localhost:8080/ws", nil)
+	var header = http.Header{}
+	if *token != "" {
+		header.Set("Authorization", "Bearer "+*token)
+	}
+
+	conn, response, err := dialer.Dial(*url, header)
 	if err != nil {
-		log.Println("WebSocket dial error:", err)
+		if response != nil && response.StatusCode == http.StatusUnauthorized {
+			slog.Error("WebSocket dial error, the server rejected the token, use -token")
+			return
+		}
+
+		slog.Error("WebSocket dial error", "error", err)
 		return
 	}
 
-	go readMessage(conn)
+	// The commands waiting for the server's ack
+	pending := newPendingRequests()
+
+	go readMessage(conn, pending)
 
 	for {
 		// Sleep for a second to prevent spamming
 		time.Sleep(10 * time.Millisecond)
 
 		fmt.Print("Enter command: ")
-		command, _ := reader.ReadString('\n')
-		command = strings.TrimSuffix(command, "\n")
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			fmt.Println("Exiting...")
+			return
+		}
 
-		switch {
-		case strings.HasPrefix(command, "unsubs "):
-			// TODO: Implement unsubs command
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
 
-		case strings.HasPrefix(command, "subs "):
-			// TODO: Implement subs command
+		command, err := parseCommand(line)
+		if err != nil {
+			fmt.Println("Invalid command:", err)
+			continue
+		}
 
-		case command == "exit":
+		if command.name == exitCommand {
 			fmt.Println("Exiting...")
 			return
-		default:
-			fmt.Println("Invalid command")
 		}
 
-		// Create a new WebSocket message
-		queryCommand := pb.SubscribeRequest{
-			Channel: command,
-		}
+		// Create a new request
+		requestID := nextRequestID()
+		queryCommand := command.clientMessage(requestID)
 
 		// Marshal the message
-		msg, err := proto.Marshal(&queryCommand)
+		msg, err := proto.Marshal(queryCommand)
 		if err != nil {
-			log.Println("Proto marshal error:", err)
+			slog.Error("Proto marshal error", "request_id", requestID, "error", err)
 			return
 		}
 
+		pending.add(requestID, command)
+		slog.Debug("Sending request", "request_id", requestID, "command", command.name)
 		sendMessage(conn, msg)
 	}
 }
 
-func readMessage(conn *websocket.Conn) {
+func readMessage(conn *websocket.Conn, pending *PendingRequests) {
+	// The last sequence number seen on each channel
+	sequences := newSequenceTracker()
+
 	for {
 		messageType, message, err := conn.ReadMessage()
 		if err != nil {
-			log.Println("WebSocket read error:", err)
+			slog.Error("WebSocket read error", "error", err)
 			return
 		}
 
@@ -89,19 +170,218 @@ func readMessage(conn *websocket.Conn) {
 		case websocket.BinaryMessage:
 			var webSocketMessage pb.WebSocketMessage
 			if err := proto.Unmarshal(message, &webSocketMessage); err != nil {
-				log.Println("Proto unmarshal error:", err)
+				slog.Error("Proto unmarshal error", "error", err)
 				return
 			}
 
-			fmt.Printf("[SERVER]: %s\n", webSocketMessage.GetSubscribeResponse().Message)
+			switch webSocketMessage.GetPaylod().(type) {
+			case *pb.WebSocketMessage_Tick:
+				receiveTick(conn, sequences, webSocketMessage.GetTick())
+
+			case *pb.WebSocketMessage_TickBatch:
+				ticks := webSocketMessage.GetTickBatch().Ticks
+				fmt.Printf("Batch of %d ticks\n", len(ticks))
+				for _, tick := range ticks {
+					receiveTick(conn, sequences, tick)
+				}
+
+			case *pb.WebSocketMessage_ResyncResponse:
+				receiveResyncResponse(conn, sequences, webSocketMessage.GetResyncResponse())
+
+			case *pb.WebSocketMessage_SubscriptionResponse:
+				response := webSocketMessage.GetSubscriptionResponse()
+				command := pending.take(response.RequestId)
+				printSubscriptionResponse(command, response)
+
+				// The sequence numbers of the unsubscribed channels jump when we
+				// subscribe to them again later
+				if command != nil && response.Success && command.name == unsubscribeCommand {
+					sequences.forget(command.channels)
+				}
+
+				// The ticks a filter skips are not missing, an unsubscribe drops the filter
+				if command != nil && response.Success {
+					for _, channel := range command.channels {
+						sequences.filter(channel, command.filter != "")
+					}
+				}
+
+			case *pb.WebSocketMessage_PublishResponse:
+				response := webSocketMessage.GetPublishResponse()
+				command := pending.take(response.RequestId)
+				printPublishResponse(command, response)
+
+				// The server leaves us out of a publish without echo, the
+				// sequence number of its tick is not missing
+				if command != nil && response.Success && command.noEcho {
+					sequences.skip(command.channels[0], response.Sequence)
+				}
+
+			case *pb.WebSocketMessage_ListChannelsResponse:
+				response := webSocketMessage.GetListChannelsResponse()
+				pending.take(response.RequestId)
+				printChannels(response.Channels)
+
+			case *pb.WebSocketMessage_ErrorMessage:
+				errorMessage := webSocketMessage.GetErrorMessage()
+				if errorMessage.RequestId != "" {
+					pending.take(errorMessage.RequestId)
+					fmt.Printf("[SERVER]: request %s failed (%s): %s\n", errorMessage.RequestId, errorMessage.ErrorCode, errorMessage.ErrorMessage)
+				} else {
+					fmt.Println("[SERVER]:", errorMessage.ErrorMessage)
+				}
+
+			default:
+				fmt.Println("undefined message type")
+			}
 		}
 	}
 }
 
+// Print a tick from the server, a gap in its channel's sequence numbers is
+// filled with a resync request
+func receiveTick(conn *websocket.Conn, sequences *SequenceTracker, tick *pb.Tick) {
+	if from, to, gap := sequences.observe(tick); gap {
+		fmt.Printf("Missed ticks %d to %d on %s, resyncing...\n", from, to, tick.Channel)
+		requestResync(conn, sequences, tick.Channel, from, to)
+	}
+
+	switch {
+	case tick.Replayed:
+		fmt.Println("(replay)", formatTick(tick))
+	case tick.Conflated:
+		fmt.Println("(conflated)", formatTick(tick))
+	default:
+		fmt.Println(formatTick(tick))
+	}
+}
+
+// Print the server's ack or nack of the command
+func printSubscriptionResponse(command *Command, response *pb.SubscriptionResponse) {
+	if !response.Success {
+		fmt.Printf("[SERVER]: request %s rejected (%s): %s\n", response.RequestId, response.ErrorCode, response.Message)
+		return
+	}
+
+	if command == nil {
+		fmt.Printf("[SERVER]: unexpected response to request %s\n", response.RequestId)
+		return
+	}
+
+	switch command.name {
+	case subscribeCommand:
+		fmt.Printf("[SERVER]: subscribed to %s\n", strings.Join(command.channels, ", "))
+	case unsubscribeCommand:
+		fmt.Printf("[SERVER]: unsubscribed from %s\n", strings.Join(command.channels, ", "))
+	}
+
+	fmt.Printf("[SERVER]: subscriptions: %s\n", joinOrNone(response.Channels))
+}
+
+// Print the server's ack or nack of a publish command
+func printPublishResponse(command *Command, response *pb.PublishResponse) {
+	if !response.Success {
+		fmt.Printf("[SERVER]: request %s rejected (%s): %s\n", response.RequestId, response.ErrorCode, response.Message)
+		return
+	}
+
+	if command == nil {
+		fmt.Printf("[SERVER]: unexpected response to request %s\n", response.RequestId)
+		return
+	}
+
+	fmt.Printf("[SERVER]: published to %s\n", command.channels[0])
+}
+
+// Ask the server for the ticks of the channel we missed
+func requestResync(conn *websocket.Conn, sequences *SequenceTracker, channel string, from uint64, to uint64) {
+	requestID := nextRequestID()
+	msg, err := proto.Marshal(&pb.ClientMessage{
+		Payload: &pb.ClientMessage_ResyncRequest{
+			ResyncRequest: &pb.ResyncRequest{
+				RequestId:    requestID,
+				Channel:      channel,
+				FromSequence: from,
+				ToSequence:   to,
+			},
+		},
+	})
+	if err != nil {
+		slog.Error("Proto marshal error", "request_id", requestID, "error", err)
+		return
+	}
+
+	slog.Debug("Requesting resync", "request_id", requestID, "channel", channel, "from", from, "to", to)
+	sequences.resyncRequested(requestID, from, to)
+	sendMessage(conn, msg)
+}
+
+// Print the ticks the server sent back for a resync, the server reads a
+// limited number of ticks at once so the rest of a longer range is asked for
+// from the one after the last tick it sent
+func receiveResyncResponse(conn *websocket.Conn, sequences *SequenceTracker, response *pb.ResyncResponse) {
+	resync, requested := sequences.resyncAnswered(response.RequestId)
+	printResyncResponse(response, resync)
+
+	if !requested || !response.Success || response.Complete || len(response.Ticks) == 0 {
+		return
+	}
+
+	if last := response.Ticks[len(response.Ticks)-1].Sequence; last < resync.to {
+		requestResync(conn, sequences, response.Channel, last+1, resync.to)
+	}
+}
+
+// Print the ticks the server sent back for the resync of the range, the
+// ticks missing from its start are no longer available
+func printResyncResponse(response *pb.ResyncResponse, resync SequenceRange) {
+	if !response.Success {
+		fmt.Printf("[SERVER]: resync of %s rejected (%s): %s\n", response.Channel, response.ErrorCode, response.Message)
+		return
+	}
+
+	if !response.Complete && (len(response.Ticks) == 0 || response.Ticks[0].Sequence > resync.from) {
+		fmt.Printf("[SERVER]: some ticks of %s are no longer available\n", response.Channel)
+	}
+
+	for _, tick := range response.Ticks {
+		fmt.Println("(resync)", formatTick(tick))
+	}
+}
+
+// Print the channels that exist on the server
+func printChannels(channels []*pb.ChannelInfo) {
+	if len(channels) == 0 {
+		fmt.Println("[SERVER]: no channels")
+		return
+	}
+
+	fmt.Println("[SERVER]: available channels:")
+	for _, channel := range channels {
+		fmt.Printf("%s: %s (publisher: %s, subscribers: %d)\n", channel.Name, channel.Description, channel.Publisher, channel.Subscribers)
+	}
+}
+
+// Join the names with a comma or return "none" if there are none
+func joinOrNone(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// The input loop and the read goroutine both send messages, gorilla/websocket
+// does not allow concurrent writers
+var writeMutex sync.Mutex
+
 // Send a message to the WebSocket connection
 func sendMessage(conn *websocket.Conn, msg []byte) {
+	writeMutex.Lock()
+	defer writeMutex.Unlock()
+
 	err := conn.WriteMessage(websocket.BinaryMessage, msg)
 	if err != nil {
-		log.Println("WebSocket write error:", err)
+		slog.Error("WebSocket write error", "error", err)
 	}
 }
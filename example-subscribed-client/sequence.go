@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strings"
+
+	pb "subscribed-client/protobuf"
+)
+
+// SequenceTracker remembers the sequence number of the last tick seen on each
+// channel so gaps can be detected, it is only used by the read goroutine
+type SequenceTracker struct {
+	last map[string]uint64
+
+	// The channel patterns subscribed to with a filter, the server skips the
+	// ticks of their channels that don't match it
+	filtered map[string]bool
+
+	// The ranges of the resync requests that weren't answered yet
+	resyncs map[string]SequenceRange
+}
+
+// SequenceRange is the sequence numbers from from to to inclusive
+type SequenceRange struct {
+	from uint64
+	to   uint64
+}
+
+// Create a new sequence tracker that hasn't seen any tick yet
+func newSequenceTracker() *SequenceTracker {
+	return &SequenceTracker{
+		last:     make(map[string]uint64),
+		filtered: make(map[string]bool),
+		resyncs:  make(map[string]SequenceRange),
+	}
+}
+
+// Set if the subscription to the channel pattern has a filter
+func (tracker *SequenceTracker) filter(pattern string, filtered bool) {
+	if filtered {
+		tracker.filtered[pattern] = true
+	} else {
+		delete(tracker.filtered, pattern)
+	}
+}
+
+// Check if the channel belongs to a subscription with a filter
+func (tracker *SequenceTracker) isFiltered(channel string) bool {
+	for pattern := range tracker.filtered {
+		if patternMatches(pattern, channel) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check if a channel name matches a subscribed pattern, '*' matches one token
+// and a trailing '>' matches one or more tokens
+func patternMatches(pattern string, channel string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	channelTokens := strings.Split(channel, ".")
+
+	for i, token := range patternTokens {
+		if token == ">" {
+			return len(channelTokens) > i
+		}
+
+		if i >= len(channelTokens) || (token != "*" && token != channelTokens[i]) {
+			return false
+		}
+	}
+
+	return len(patternTokens) == len(channelTokens)
+}
+
+// Record the tick and return the range of sequence numbers that were missed
+// before it, gap is false when the tick follows the last one seen or when the
+// server conflated the ticks before it on purpose
+func (tracker *SequenceTracker) observe(tick *pb.Tick) (from uint64, to uint64, gap bool) {
+	last, seen := tracker.last[tick.Channel]
+	if seen && tick.Sequence <= last {
+		return 0, 0, false
+	}
+
+	tracker.last[tick.Channel] = tick.Sequence
+
+	if !seen || tick.Sequence == last+1 || tick.Conflated || tracker.isFiltered(tick.Channel) {
+		return 0, 0, false
+	}
+
+	return last + 1, tick.Sequence - 1, true
+}
+
+// Mark the sequence number of a tick we published without echo as seen, the
+// server leaves us out of it so it is not missing. The ticks before it are
+// sent before the publish response, so it follows the last tick seen
+func (tracker *SequenceTracker) skip(channel string, sequence uint64) {
+	if last, seen := tracker.last[channel]; seen && sequence == last+1 {
+		tracker.last[channel] = sequence
+	}
+}
+
+// Forget the channels matching any of the patterns, the next tick of each of
+// them is not checked for a gap
+func (tracker *SequenceTracker) forget(patterns []string) {
+	for channel := range tracker.last {
+		for _, pattern := range patterns {
+			if patternMatches(pattern, channel) {
+				delete(tracker.last, channel)
+				break
+			}
+		}
+	}
+}
+
+// Remember the range asked for by a resync request
+func (tracker *SequenceTracker) resyncRequested(requestID string, from uint64, to uint64) {
+	tracker.resyncs[requestID] = SequenceRange{from: from, to: to}
+}
+
+// Get the range asked for by the resync request that was answered, the
+// second return value is false for an unknown request
+func (tracker *SequenceTracker) resyncAnswered(requestID string) (SequenceRange, bool) {
+	resync, ok := tracker.resyncs[requestID]
+	delete(tracker.resyncs, requestID)
+
+	return resync, ok
+}
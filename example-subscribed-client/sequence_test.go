@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+
+	pb "subscribed-client/protobuf"
+)
+
+func TestSequenceTrackerGap(t *testing.T) {
+	tracker := newSequenceTracker()
+
+	tests := []struct {
+		tick     *pb.Tick
+		from, to uint64
+		gap      bool
+	}{
+		{tick: &pb.Tick{Channel: "btc", Sequence: 5}},
+		{tick: &pb.Tick{Channel: "btc", Sequence: 6}},
+		{tick: &pb.Tick{Channel: "btc", Sequence: 9}, from: 7, to: 8, gap: true},
+		{tick: &pb.Tick{Channel: "btc", Sequence: 8}},
+		{tick: &pb.Tick{Channel: "btc", Sequence: 12, Conflated: true}},
+		{tick: &pb.Tick{Channel: "eth", Sequence: 1}},
+	}
+
+	for i, test := range tests {
+		from, to, gap := tracker.observe(test.tick)
+		if from != test.from || to != test.to || gap != test.gap {
+			t.Errorf("tick %d: observe(%s %d) = %d, %d, %v, want %d, %d, %v", i, test.tick.Channel, test.tick.Sequence, from, to, gap, test.from, test.to, test.gap)
+		}
+	}
+}
+
+func TestSequenceTrackerForget(t *testing.T) {
+	tracker := newSequenceTracker()
+	for _, channel := range []string{"crypto.btc", "crypto.eth", "stocks.aapl"} {
+		tracker.observe(&pb.Tick{Channel: channel, Sequence: 1})
+	}
+
+	tracker.forget([]string{"crypto.btc"})
+
+	// A forgotten channel starts over without a gap
+	if _, _, gap := tracker.observe(&pb.Tick{Channel: "crypto.btc", Sequence: 50}); gap {
+		t.Error("crypto.btc was not forgotten")
+	}
+
+	// The channels that are still subscribed keep their sequence numbers
+	if _, _, gap := tracker.observe(&pb.Tick{Channel: "crypto.eth", Sequence: 5}); !gap {
+		t.Error("crypto.eth was forgotten by an unsubscribe from crypto.btc")
+	}
+	if _, _, gap := tracker.observe(&pb.Tick{Channel: "stocks.aapl", Sequence: 5}); !gap {
+		t.Error("stocks.aapl was forgotten by an unsubscribe from crypto.btc")
+	}
+
+	tracker.forget([]string{"stocks.>"})
+	if _, _, gap := tracker.observe(&pb.Tick{Channel: "stocks.aapl", Sequence: 20}); gap {
+		t.Error("stocks.aapl was not forgotten by an unsubscribe from stocks.>")
+	}
+	if _, _, gap := tracker.observe(&pb.Tick{Channel: "crypto.eth", Sequence: 10}); !gap {
+		t.Error("crypto.eth was forgotten by an unsubscribe from stocks.>")
+	}
+}
+
+func TestPatternMatches(t *testing.T) {
+	tests := []struct {
+		pattern, channel string
+		want             bool
+	}{
+		{"btc", "btc", true},
+		{"btc", "eth", false},
+		{"crypto.*", "crypto.btc", true},
+		{"crypto.*", "crypto.btc.usd", false},
+		{"crypto.>", "crypto.btc.usd", true},
+		{"crypto.>", "crypto", false},
+		{">", "btc", true},
+	}
+
+	for _, test := range tests {
+		if got := patternMatches(test.pattern, test.channel); got != test.want {
+			t.Errorf("patternMatches(%q, %q) = %v, want %v", test.pattern, test.channel, got, test.want)
+		}
+	}
+}
+
+func TestSequenceTrackerSkip(t *testing.T) {
+	tracker := newSequenceTracker()
+	tracker.observe(&pb.Tick{Channel: "chat", Sequence: 1})
+
+	// The tick we published without echo is never sent back to us
+	tracker.skip("chat", 2)
+	if from, to, gap := tracker.observe(&pb.Tick{Channel: "chat", Sequence: 3}); gap {
+		t.Errorf("missed ticks %d to %d after our own publish", from, to)
+	}
+
+	// A tick missed before our publish is still a gap
+	tracker.skip("chat", 5)
+	if from, to, gap := tracker.observe(&pb.Tick{Channel: "chat", Sequence: 6}); !gap || from != 4 || to != 5 {
+		t.Errorf("observe(chat 6) = %d, %d, %v, want 4, 5, true", from, to, gap)
+	}
+
+	// Nothing is seen yet on a channel we publish to without a subscription
+	tracker.skip("news", 1)
+	if _, _, gap := tracker.observe(&pb.Tick{Channel: "news", Sequence: 7}); gap {
+		t.Error("the first tick of a channel is a gap after a publish to it")
+	}
+}
+
+func TestSequenceTrackerResyncs(t *testing.T) {
+	tracker := newSequenceTracker()
+	tracker.resyncRequested("7", 11, 2500)
+
+	if resync, ok := tracker.resyncAnswered("7"); !ok || resync.from != 11 || resync.to != 2500 {
+		t.Errorf("resyncAnswered(7) = %v, %v, want 11 to 2500", resync, ok)
+	}
+
+	// A request is only answered once
+	if _, ok := tracker.resyncAnswered("7"); ok {
+		t.Error("resync 7 was answered twice")
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "subscribed-client/protobuf"
+)
+
+// Format a tick as "[channel #sequence time] value key=value..."
+func formatTick(tick *pb.Tick) string {
+	var builder strings.Builder
+
+	timestamp := time.Unix(0, tick.Timestamp).Format("15:04:05.000")
+	fmt.Fprintf(&builder, "[%s #%d %s] %s", tick.Channel, tick.Sequence, timestamp, formatTickValue(tick))
+
+	// Print the metadata sorted by key so the output is stable
+	var keys []string
+	for key := range tick.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(&builder, " %s=%s", key, tick.Metadata[key])
+	}
+
+	return builder.String()
+}
+
+// Format the value of a tick, decimals are printed exactly with all their digits
+func formatTickValue(tick *pb.Tick) string {
+	switch value := tick.GetValue().(type) {
+	case *pb.Tick_DoubleValue:
+		return strconv.FormatFloat(value.DoubleValue, 'f', -1, 64)
+	case *pb.Tick_DecimalValue:
+		return formatDecimal(value.DecimalValue)
+	case *pb.Tick_Text:
+		return strconv.Quote(value.Text)
+	default:
+		return "<empty>"
+	}
+}
+
+// Format a fixed-point decimal as units * 10^-scale
+func formatDecimal(decimal *pb.Decimal) string {
+	if decimal.Scale <= 0 {
+		return strconv.FormatInt(decimal.Units, 10) + strings.Repeat("0", int(-decimal.Scale))
+	}
+
+	var sign string
+	digits := strconv.FormatInt(decimal.Units, 10)
+	if decimal.Units < 0 {
+		sign, digits = "-", digits[1:]
+	}
+
+	// Pad with zeros so there is at least one digit before the point
+	scale := int(decimal.Scale)
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
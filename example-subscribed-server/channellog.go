@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "handle-subscribed/protobuf"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// The extension of the segment files, a segment is named after the sequence
+// number of its first tick
+const segmentExtension = ".log"
+
+// The largest record a segment may hold, a length above it can only come from
+// a corrupt or partly written header
+const maxRecordSize = 1 << 20
+
+// The default size of a segment and of the log of a channel in bytes
+const (
+	defaultLogSegmentSize = 1 << 20
+	defaultLogMaxSize     = 100 << 20
+)
+
+// The default number of ticks read from the log for one replay or resync, the
+// log is read by the run goroutine so a read must stay short
+const defaultLogReadLimit = 1000
+
+// LogOptions configures when segments are rolled and removed, a zero
+// maxBytes or maxAge keeps segments regardless of size or age, the age is
+// measured by the clock
+type LogOptions struct {
+	segmentBytes int64
+	maxBytes     int64
+	maxAge       time.Duration
+	clock        Clock
+}
+
+// LogStore keeps a ChannelLog per channel in a directory per channel, it is
+// only used by the run goroutine
+type LogStore struct {
+	dir     string
+	options LogOptions
+	logs    map[string]*ChannelLog
+}
+
+// ChannelLog appends the ticks of a channel to segment files, each record is
+// the length of the marshaled tick as a big endian uint32 followed by the tick.
+// Appends are not synced to disk, the last ticks written before a machine
+// crash may be lost and a record cut off by it is dropped on the next open
+type ChannelLog struct {
+	dir      string
+	options  LogOptions
+	segments []*Segment
+	active   *os.File
+}
+
+// Segment is a file of consecutive ticks of a channel
+type Segment struct {
+	path          string
+	firstSequence uint64
+	lastSequence  uint64
+	lastTimestamp int64
+	size          int64
+}
+
+// Open the log store in the directory and every channel log already in it
+func openLogStore(dir string, options LogOptions) (*LogStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	if options.segmentBytes <= 0 {
+		options.segmentBytes = defaultLogSegmentSize
+	}
+
+	if options.clock == nil {
+		options.clock = RealClock{}
+	}
+
+	var store = &LogStore{
+		dir:     dir,
+		options: options,
+		logs:    make(map[string]*ChannelLog),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !channelNamePattern.MatchString(entry.Name()) {
+			continue
+		}
+
+		if _, err := store.log(entry.Name()); err != nil {
+			store.close()
+			return nil, err
+		}
+	}
+
+	return store, nil
+}
+
+// Get the names of the channels that have a log sorted by name
+func (store *LogStore) channels() []string {
+	var channels []string
+	for channel := range store.logs {
+		channels = append(channels, channel)
+	}
+
+	sort.Strings(channels)
+
+	return channels
+}
+
+// Get the log of the channel, it is created if it doesn't exist yet
+func (store *LogStore) log(channel string) (*ChannelLog, error) {
+	if channelLog, ok := store.logs[channel]; ok {
+		return channelLog, nil
+	}
+
+	channelLog, err := openChannelLog(filepath.Join(store.dir, channel), store.options)
+	if err != nil {
+		return nil, fmt.Errorf("opening log of channel %q: %w", channel, err)
+	}
+
+	store.logs[channel] = channelLog
+
+	return channelLog, nil
+}
+
+// Close the logs of all channels
+func (store *LogStore) close() {
+	for _, channelLog := range store.logs {
+		channelLog.close()
+	}
+}
+
+// Open the channel log in the directory and recover its segments, a record
+// that was only partly written to the last segment is cut off
+func openChannelLog(dir string, options LogOptions) (*ChannelLog, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+segmentExtension))
+	if err != nil {
+		return nil, err
+	}
+
+	var channelLog = &ChannelLog{dir: dir, options: options}
+	for _, path := range paths {
+		firstSequence, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(path), segmentExtension), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		segment, err := scanSegment(path, firstSequence)
+		if err != nil {
+			return nil, err
+		}
+
+		channelLog.segments = append(channelLog.segments, segment)
+	}
+
+	sort.Slice(channelLog.segments, func(i, j int) bool {
+		return channelLog.segments[i].firstSequence < channelLog.segments[j].firstSequence
+	})
+
+	if len(channelLog.segments) > 0 {
+		last := channelLog.segments[len(channelLog.segments)-1]
+		if err := os.Truncate(last.path, last.size); err != nil {
+			return nil, err
+		}
+
+		channelLog.active, err = os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return channelLog, nil
+}
+
+// Read the records of a segment file to find its last tick and the size of
+// the complete records in it
+func scanSegment(path string, firstSequence uint64) (*Segment, error) {
+	var segment = &Segment{path: path, firstSequence: firstSequence, lastSequence: firstSequence - 1}
+
+	err := readSegment(path, func(tick *pb.Tick, size int64) bool {
+		segment.lastSequence = tick.Sequence
+		segment.lastTimestamp = tick.Timestamp
+		segment.size += size
+		return true
+	})
+
+	return segment, err
+}
+
+// Call fn with each complete record of the segment file and its size on disk
+// until fn returns false, a partial record or a length above maxRecordSize is
+// the end of the segment
+func readSegment(path string, fn func(tick *pb.Tick, size int64) bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var header [4]byte
+	for {
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			// A missing or partial header is the end of the segment
+			return nil
+		}
+
+		length := binary.BigEndian.Uint32(header[:])
+		if length > maxRecordSize {
+			return nil
+		}
+
+		var data = make([]byte, length)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil
+		}
+
+		var tick = &pb.Tick{}
+		if err := proto.Unmarshal(data, tick); err != nil {
+			return fmt.Errorf("corrupt record in %s: %w", path, err)
+		}
+
+		if !fn(tick, int64(len(header)+len(data))) {
+			return nil
+		}
+	}
+}
+
+// Get the sequence number of the last tick in the log, 0 if the log is empty
+func (channelLog *ChannelLog) lastSequence() uint64 {
+	if len(channelLog.segments) == 0 {
+		return 0
+	}
+
+	return channelLog.segments[len(channelLog.segments)-1].lastSequence
+}
+
+// Get the sequence number of the first tick still kept in the log
+func (channelLog *ChannelLog) firstSequence() uint64 {
+	if len(channelLog.segments) == 0 {
+		return 0
+	}
+
+	return channelLog.segments[0].firstSequence
+}
+
+// Append the tick to the active segment without syncing it, a new segment is
+// started when the active one is full and old segments are removed by the
+// retention options
+func (channelLog *ChannelLog) append(tick *pb.Tick) error {
+	data, err := proto.Marshal(tick)
+	if err != nil {
+		return err
+	}
+
+	if len(data) > maxRecordSize {
+		return fmt.Errorf("tick of %d bytes is larger than the largest record of %d bytes", len(data), maxRecordSize)
+	}
+
+	if channelLog.active == nil || channelLog.activeSegment().size >= channelLog.options.segmentBytes {
+		if err := channelLog.roll(tick.Sequence); err != nil {
+			return err
+		}
+	}
+
+	var record = make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(record, uint32(len(data)))
+	copy(record[4:], data)
+
+	if _, err := channelLog.active.Write(record); err != nil {
+		return err
+	}
+
+	segment := channelLog.activeSegment()
+	segment.lastSequence = tick.Sequence
+	segment.lastTimestamp = tick.Timestamp
+	segment.size += int64(len(record))
+
+	return channelLog.enforceRetention()
+}
+
+// Get the segment ticks are appended to
+func (channelLog *ChannelLog) activeSegment() *Segment {
+	return channelLog.segments[len(channelLog.segments)-1]
+}
+
+// Close the active segment and start a new one with the sequence number
+func (channelLog *ChannelLog) roll(firstSequence uint64) error {
+	if channelLog.active != nil {
+		if err := channelLog.active.Close(); err != nil {
+			return err
+		}
+	}
+
+	path := filepath.Join(channelLog.dir, fmt.Sprintf("%020d%s", firstSequence, segmentExtension))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	channelLog.active = file
+	channelLog.segments = append(channelLog.segments, &Segment{
+		path:          path,
+		firstSequence: firstSequence,
+		lastSequence:  firstSequence - 1,
+	})
+
+	return nil
+}
+
+// Remove the oldest segments while the log is larger than the maximum size
+// or their newest tick is older than the maximum age, the active segment is
+// always kept
+func (channelLog *ChannelLog) enforceRetention() error {
+	var total int64
+	for _, segment := range channelLog.segments {
+		total += segment.size
+	}
+
+	var cutoff = channelLog.options.clock.Now().Add(-channelLog.options.maxAge).UnixNano()
+	for len(channelLog.segments) > 1 {
+		oldest := channelLog.segments[0]
+		tooLarge := channelLog.options.maxBytes > 0 && total > channelLog.options.maxBytes
+		tooOld := channelLog.options.maxAge > 0 && oldest.lastTimestamp < cutoff
+		if !tooLarge && !tooOld {
+			break
+		}
+
+		if err := os.Remove(oldest.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		total -= oldest.size
+		channelLog.segments = channelLog.segments[1:]
+	}
+
+	return nil
+}
+
+// Read the ticks with a sequence number from from to to inclusive, a to of 0
+// reads up to the last tick and a limit above 0 reads only the first limit
+// ticks of the range so the rest can be read from the one after the last, the
+// second return value is false if some of the ticks were already removed from
+// the log or were left out by the limit
+func (channelLog *ChannelLog) read(from uint64, to uint64, limit int) ([]*pb.Tick, bool, error) {
+	if to == 0 {
+		to = channelLog.lastSequence()
+	}
+
+	var complete = true
+	if first := channelLog.firstSequence(); from < first {
+		from = first
+		complete = false
+	}
+
+	if limit > 0 && to >= from && to-from >= uint64(limit) {
+		to = from + uint64(limit) - 1
+		complete = false
+	}
+
+	var ticks []*pb.Tick
+	for _, segment := range channelLog.segments {
+		if segment.lastSequence < from || segment.firstSequence > to {
+			continue
+		}
+
+		err := readSegment(segment.path, func(tick *pb.Tick, size int64) bool {
+			if tick.Sequence >= from && tick.Sequence <= to {
+				ticks = append(ticks, tick)
+			}
+			return tick.Sequence < to
+		})
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	return ticks, complete, nil
+}
+
+// Read the ticks published at or after the timestamp in unix nanoseconds, a
+// limit above 0 reads only the first limit of them and the second return
+// value is then false if there are more
+func (channelLog *ChannelLog) readSince(timestamp int64, limit int) ([]*pb.Tick, bool, error) {
+	for _, segment := range channelLog.segments {
+		// Skip the segments with only older ticks
+		if segment.lastTimestamp < timestamp {
+			continue
+		}
+
+		var from uint64
+		err := readSegment(segment.path, func(tick *pb.Tick, size int64) bool {
+			from = tick.Sequence
+			return tick.Timestamp < timestamp
+		})
+		if err != nil {
+			return nil, false, err
+		}
+
+		return channelLog.read(from, 0, limit)
+	}
+
+	return nil, true, nil
+}
+
+// Close the active segment
+func (channelLog *ChannelLog) close() {
+	if channelLog.active != nil {
+		channelLog.active.Close()
+		channelLog.active = nil
+	}
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "handle-subscribed/protobuf"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// The time the ticks of the tests are published at
+var logTestStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Create the tick with the sequence number published the sequence number of
+// seconds after logTestStart, every tick below 128 has the same size
+func logTestTick(sequence uint64) *pb.Tick {
+	return &pb.Tick{
+		Channel:   "btc",
+		Sequence:  sequence,
+		Timestamp: logTestStart.Add(time.Duration(sequence) * time.Second).UnixNano(),
+		Value:     &pb.Tick_DoubleValue{DoubleValue: float64(sequence)},
+	}
+}
+
+// Get the size on disk of the record of a test tick
+func logTestRecordSize() int64 {
+	return int64(4 + proto.Size(logTestTick(1)))
+}
+
+// Open a channel log in a temporary directory and append the ticks from 1 to
+// last to it
+func openTestChannelLog(t *testing.T, dir string, options LogOptions, last uint64) *ChannelLog {
+	t.Helper()
+
+	if options.clock == nil {
+		options.clock = RealClock{}
+	}
+
+	channelLog, err := openChannelLog(dir, options)
+	if err != nil {
+		t.Fatalf("openChannelLog: %v", err)
+	}
+	t.Cleanup(channelLog.close)
+
+	for sequence := channelLog.lastSequence() + 1; sequence <= last; sequence++ {
+		if err := channelLog.append(logTestTick(sequence)); err != nil {
+			t.Fatalf("append %d: %v", sequence, err)
+		}
+	}
+
+	return channelLog
+}
+
+// Get the sequence numbers of the ticks
+func tickSequences(ticks []*pb.Tick) []uint64 {
+	var sequences []uint64
+	for _, tick := range ticks {
+		sequences = append(sequences, tick.Sequence)
+	}
+
+	return sequences
+}
+
+// Check that the sequence numbers are from to to inclusive
+func checkSequenceRange(t *testing.T, got []uint64, from uint64, to uint64) {
+	t.Helper()
+
+	var want []uint64
+	for sequence := from; sequence <= to && from != 0; sequence++ {
+		want = append(want, sequence)
+	}
+
+	if !equalSequences(got, want) {
+		t.Fatalf("got sequences %v, want %v", got, want)
+	}
+}
+
+// Check if the sequence numbers are the same
+func equalSequences(a []uint64, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestChannelLogRoll(t *testing.T) {
+	dir := t.TempDir()
+	channelLog := openTestChannelLog(t, dir, LogOptions{segmentBytes: 3 * logTestRecordSize()}, 10)
+
+	var firstSequences []uint64
+	for _, segment := range channelLog.segments {
+		firstSequences = append(firstSequences, segment.firstSequence)
+		if _, err := os.Stat(segment.path); err != nil {
+			t.Errorf("segment %d: %v", segment.firstSequence, err)
+		}
+	}
+
+	if want := []uint64{1, 4, 7, 10}; !equalSequences(firstSequences, want) {
+		t.Fatalf("segments start at %v, want %v", firstSequences, want)
+	}
+
+	if got := filepath.Base(channelLog.segments[3].path); got != "00000000000000000010.log" {
+		t.Errorf("last segment is named %s, want 00000000000000000010.log", got)
+	}
+
+	if channelLog.firstSequence() != 1 || channelLog.lastSequence() != 10 {
+		t.Errorf("log has ticks %d to %d, want 1 to 10", channelLog.firstSequence(), channelLog.lastSequence())
+	}
+}
+
+func TestChannelLogRetentionBySize(t *testing.T) {
+	dir := t.TempDir()
+	options := LogOptions{segmentBytes: 2 * logTestRecordSize(), maxBytes: 5 * logTestRecordSize()}
+	channelLog := openTestChannelLog(t, dir, options, 10)
+
+	// The oldest segments are removed until the rest fit in five records
+	if got := channelLog.firstSequence(); got != 7 {
+		t.Fatalf("first sequence is %d after retention, want 7", got)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "00000000000000000001.log")); !os.IsNotExist(err) {
+		t.Errorf("the oldest segment was not removed: %v", err)
+	}
+
+	ticks, complete, err := channelLog.read(1, 0, 0)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	if complete {
+		t.Error("read of removed ticks is complete")
+	}
+
+	checkSequenceRange(t, tickSequences(ticks), 7, 10)
+}
+
+func TestChannelLogRetentionByAge(t *testing.T) {
+	clock := newManualClock(logTestStart)
+	options := LogOptions{segmentBytes: 2 * logTestRecordSize(), maxAge: 5 * time.Second, clock: clock}
+	channelLog := openTestChannelLog(t, t.TempDir(), options, 4)
+
+	// The ticks are published a second apart so none of them is too old yet
+	clock.advance(5 * time.Second)
+	if err := channelLog.append(logTestTick(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := channelLog.firstSequence(); got != 1 {
+		t.Fatalf("first sequence is %d before the ticks are too old, want 1", got)
+	}
+
+	// The newest tick of the first segment is 2s old and falls out of 5s
+	// once the clock is at 8s, the active segment is always kept
+	clock.advance(3 * time.Second)
+	if err := channelLog.append(logTestTick(6)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := channelLog.firstSequence(); got != 3 {
+		t.Fatalf("first sequence is %d after the first segment is too old, want 3", got)
+	}
+
+	clock.advance(time.Hour)
+	if err := channelLog.append(logTestTick(7)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := channelLog.firstSequence(); got != 7 {
+		t.Fatalf("first sequence is %d after every full segment is too old, want 7", got)
+	}
+}
+
+func TestChannelLogRecovery(t *testing.T) {
+	dir := t.TempDir()
+	options := LogOptions{segmentBytes: 3 * logTestRecordSize()}
+	channelLog := openTestChannelLog(t, dir, options, 5)
+	lastPath := channelLog.activeSegment().path
+	channelLog.close()
+
+	info, err := os.Stat(lastPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	completeSize := info.Size()
+
+	// Write the header and half the data of the next record like a crash
+	// in the middle of an append would
+	data, err := proto.Marshal(logTestTick(6))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var record = make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(record, uint32(len(data)))
+	copy(record[4:], data)
+	appendToFile(t, lastPath, record[:4+len(data)/2])
+
+	channelLog = openTestChannelLog(t, dir, options, 0)
+	if got := channelLog.lastSequence(); got != 5 {
+		t.Fatalf("last sequence is %d after recovery, want 5", got)
+	}
+
+	if info, err := os.Stat(lastPath); err != nil || info.Size() != completeSize {
+		t.Fatalf("the partial record was not cut off: %v", err)
+	}
+
+	if err := channelLog.append(logTestTick(6)); err != nil {
+		t.Fatal(err)
+	}
+
+	ticks, complete, err := channelLog.read(1, 0, 0)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	if !complete {
+		t.Error("read of every tick is not complete")
+	}
+
+	checkSequenceRange(t, tickSequences(ticks), 1, 6)
+}
+
+func TestChannelLogRecoveryOversizedRecord(t *testing.T) {
+	dir := t.TempDir()
+	options := LogOptions{segmentBytes: 3 * logTestRecordSize()}
+	channelLog := openTestChannelLog(t, dir, options, 4)
+	lastPath := channelLog.activeSegment().path
+	channelLog.close()
+
+	// A corrupt header with a huge length must not be allocated
+	appendToFile(t, lastPath, []byte{0xff, 0xff, 0xff, 0xff, 1, 2, 3})
+
+	channelLog = openTestChannelLog(t, dir, options, 0)
+	if got := channelLog.lastSequence(); got != 4 {
+		t.Fatalf("last sequence is %d after recovery, want 4", got)
+	}
+
+	if err := channelLog.append(logTestTick(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	ticks, _, err := channelLog.read(1, 0, 0)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	checkSequenceRange(t, tickSequences(ticks), 1, 5)
+}
+
+func TestChannelLogRead(t *testing.T) {
+	channelLog := openTestChannelLog(t, t.TempDir(), LogOptions{segmentBytes: 3 * logTestRecordSize()}, 10)
+
+	tests := []struct {
+		from, to         uint64
+		limit            int
+		wantFrom, wantTo uint64
+		wantComplete     bool
+	}{
+		{from: 1, to: 0, wantFrom: 1, wantTo: 10, wantComplete: true},
+		{from: 5, to: 0, wantFrom: 5, wantTo: 10, wantComplete: true},
+		{from: 2, to: 8, wantFrom: 2, wantTo: 8, wantComplete: true},
+		{from: 4, to: 4, wantFrom: 4, wantTo: 4, wantComplete: true},
+		{from: 10, to: 20, wantFrom: 10, wantTo: 10, wantComplete: true},
+		{from: 11, to: 0, wantComplete: true},
+
+		// The limit keeps the oldest ticks of the range
+		{from: 1, to: 0, limit: 4, wantFrom: 1, wantTo: 4, wantComplete: false},
+		{from: 2, to: 8, limit: 3, wantFrom: 2, wantTo: 4, wantComplete: false},
+		{from: 2, to: 8, limit: 7, wantFrom: 2, wantTo: 8, wantComplete: true},
+		{from: 1, to: 2, limit: 5, wantFrom: 1, wantTo: 2, wantComplete: true},
+		{from: 8, to: 0, limit: 3, wantFrom: 8, wantTo: 10, wantComplete: true},
+	}
+
+	for _, test := range tests {
+		ticks, complete, err := channelLog.read(test.from, test.to, test.limit)
+		if err != nil {
+			t.Fatalf("read(%d, %d, %d): %v", test.from, test.to, test.limit, err)
+		}
+
+		if complete != test.wantComplete {
+			t.Errorf("read(%d, %d, %d) complete = %v, want %v", test.from, test.to, test.limit, complete, test.wantComplete)
+		}
+
+		checkSequenceRange(t, tickSequences(ticks), test.wantFrom, test.wantTo)
+	}
+}
+
+func TestChannelLogReadSince(t *testing.T) {
+	channelLog := openTestChannelLog(t, t.TempDir(), LogOptions{segmentBytes: 3 * logTestRecordSize()}, 10)
+
+	tests := []struct {
+		since            time.Duration
+		limit            int
+		wantFrom, wantTo uint64
+		wantComplete     bool
+	}{
+		{since: 0, wantFrom: 1, wantTo: 10, wantComplete: true},
+		{since: 4 * time.Second, wantFrom: 4, wantTo: 10, wantComplete: true},
+		{since: 4*time.Second + time.Millisecond, wantFrom: 5, wantTo: 10, wantComplete: true},
+		{since: 10 * time.Second, wantFrom: 10, wantTo: 10, wantComplete: true},
+		{since: time.Minute, wantComplete: true},
+		{since: 0, limit: 3, wantFrom: 1, wantTo: 3, wantComplete: false},
+		{since: 4 * time.Second, limit: 7, wantFrom: 4, wantTo: 10, wantComplete: true},
+	}
+
+	for _, test := range tests {
+		ticks, complete, err := channelLog.readSince(logTestStart.Add(test.since).UnixNano(), test.limit)
+		if err != nil {
+			t.Fatalf("readSince(%s, %d): %v", test.since, test.limit, err)
+		}
+
+		if complete != test.wantComplete {
+			t.Errorf("readSince(%s, %d) complete = %v, want %v", test.since, test.limit, complete, test.wantComplete)
+		}
+
+		checkSequenceRange(t, tickSequences(ticks), test.wantFrom, test.wantTo)
+	}
+}
+
+func TestChannelLogAppendTooLarge(t *testing.T) {
+	channelLog := openTestChannelLog(t, t.TempDir(), LogOptions{}, 0)
+
+	tick := logTestTick(1)
+	tick.Value = &pb.Tick_Text{Text: string(make([]byte, maxRecordSize))}
+	if err := channelLog.append(tick); err == nil {
+		t.Fatal("a tick larger than the largest record was appended")
+	}
+}
+
+// Append the bytes to the end of the file
+func appendToFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResyncFromLogLimit(t *testing.T) {
+	server := newTestServer(&Channel{Name: "btc"})
+	server.historySize = 5
+	server.logReadLimit = 10
+
+	store, err := openLogStore(t.TempDir(), LogOptions{})
+	if err != nil {
+		t.Fatalf("openLogStore: %v", err)
+	}
+	t.Cleanup(store.close)
+	server.logStore = store
+
+	for i := 0; i < 30; i++ {
+		if err := server.publish(newTick("btc"), nil); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+
+	// The memory history only has the last 5 ticks, the log has all of them
+	// but a resync reads at most the first 10 and the rest is asked for next
+	response := server.handleResyncRequest(&pb.ResyncRequest{RequestId: "1", Channel: "btc", FromSequence: 1})
+	if !response.Success || response.Complete {
+		t.Fatalf("got success %v complete %v, want an incomplete success", response.Success, response.Complete)
+	}
+	checkSequenceRange(t, tickSequences(response.Ticks), 1, 10)
+
+	response = server.handleResyncRequest(&pb.ResyncRequest{RequestId: "2", Channel: "btc", FromSequence: 11, ToSequence: 25})
+	if !response.Success || response.Complete {
+		t.Fatalf("got success %v complete %v, want an incomplete success", response.Success, response.Complete)
+	}
+	checkSequenceRange(t, tickSequences(response.Ticks), 11, 20)
+
+	// A range within the limit is read as a whole
+	response = server.handleResyncRequest(&pb.ResyncRequest{RequestId: "3", Channel: "btc", FromSequence: 3, ToSequence: 12})
+	if !response.Success || !response.Complete {
+		t.Fatalf("got success %v complete %v, want a complete success", response.Success, response.Complete)
+	}
+	checkSequenceRange(t, tickSequences(response.Ticks), 3, 12)
+}
+
+func TestReplayFromLogLimit(t *testing.T) {
+	server := newTestServer(&Channel{Name: "btc"})
+	server.logReadLimit = 10
+
+	store, err := openLogStore(t.TempDir(), LogOptions{})
+	if err != nil {
+		t.Fatalf("openLogStore: %v", err)
+	}
+	t.Cleanup(store.close)
+	server.logStore = store
+
+	for i := 0; i < 30; i++ {
+		if err := server.publish(newTick("btc"), nil); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+
+	// A replay past the limit starts at the sequence number asked for, the
+	// rest is a gap before the next live tick
+	history := server.channelHistory("btc")
+	options := &pb.ReplayOptions{From: &pb.ReplayOptions_FromSequence{FromSequence: 1}}
+	checkSequenceRange(t, tickSequences(server.replayTicks("btc", history, options)), 1, 10)
+
+	options = &pb.ReplayOptions{From: &pb.ReplayOptions_FromTimestamp{FromTimestamp: 0}}
+	checkSequenceRange(t, tickSequences(server.replayTicks("btc", history, options)), 1, 10)
+
+	options = &pb.ReplayOptions{From: &pb.ReplayOptions_FromSequence{FromSequence: 25}}
+	checkSequenceRange(t, tickSequences(server.replayTicks("btc", history, options)), 25, 30)
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	pb "handle-subscribed/protobuf"
+	"shared/auth"
+	"shared/logging"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The default number of messages that can be queued for a client before new
+// messages are dropped, so a slow client can't block the broadcast loop
+const clientSendBufferSize = 256
+
+// The default limits of a batch of ticks sent in one frame
+const (
+	defaultBatchMaxTicks = 100
+	defaultBatchMaxBytes = 64 << 10
+)
+
+// The field numbers of the tick in a WebSocketMessage and of the tick batch
+// and its ticks, a batch is built from the marshaled tick frames
+const (
+	tickFieldNumber       = 6
+	tickBatchFieldNumber  = 8
+	batchTicksFieldNumber = 1
+)
+
+// BatchOptions configures how ticks are batched into one frame for a client,
+// a batch is sent when it holds maxTicks ticks or maxBytes bytes or when its
+// first tick has waited for latency, a zero latency sends every tick in its
+// own frame
+type BatchOptions struct {
+	maxTicks int
+	maxBytes int
+	latency  time.Duration
+	clock    Clock
+}
+
+// Client wraps a websocket connection so that every write goes through a
+// single goroutine, gorilla/websocket does not allow concurrent writers. The
+// identity is who the connection authenticated as and the messages queued
+// and dropped are counted in the metrics
+type Client struct {
+	conn     *websocket.Conn
+	identity *auth.Identity
+	send     chan *OutgoingMessage
+	batch    BatchOptions
+	metrics  *Metrics
+	logger   *slog.Logger
+}
+
+// OutgoingMessage is a message queued to be written to a client, ticks is the
+// number of ticks when data is a marshaled tick or tick batch frame that can
+// be batched
+type OutgoingMessage struct {
+	messageType int
+	data        []byte
+	ticks       int
+}
+
+// Create a new client for the websocket connection of the identity that
+// queues up to sendBufferSize messages, batches ticks with the options and
+// counts its messages in the metrics
+func newClient(conn *websocket.Conn, identity *auth.Identity, sendBufferSize int, batch BatchOptions, metrics *Metrics) *Client {
+	return &Client{
+		conn:     conn,
+		identity: identity,
+		send:     make(chan *OutgoingMessage, sendBufferSize),
+		batch:    batch,
+		metrics:  metrics,
+		logger:   logging.ConnectionLogger(conn.RemoteAddr().String(), identity.Subject),
+	}
+}
+
+// This a goroutine that writes the queued messages to the websocket connection
+// until the send channel is closed, ticks are collected into batches when
+// batching is enabled
+func (client *Client) handleOutgoingMessage() {
+	if err := client.writeMessages(); err != nil {
+		client.logger.Warn("Error writing message", "error", err)
+	}
+
+	// Drain the channel so the senders never block on a dead connection
+	for range client.send {
+	}
+}
+
+// Write the queued messages until the send channel is closed or a write fails
+func (client *Client) writeMessages() error {
+	var batch []byte
+	var batchTicks int
+	var deadline <-chan time.Time
+
+	// Send the batched ticks as one TickBatch frame
+	flush := func() error {
+		deadline = nil
+		if batchTicks == 0 {
+			return nil
+		}
+
+		frame := protowire.AppendTag(nil, tickBatchFieldNumber, protowire.BytesType)
+		frame = protowire.AppendBytes(frame, batch)
+		batch, batchTicks = batch[:0], 0
+
+		return client.conn.WriteMessage(websocket.BinaryMessage, frame)
+	}
+
+	for {
+		select {
+		case message, ok := <-client.send:
+			if !ok {
+				return flush()
+			}
+
+			if message.ticks > 0 && client.batch.latency > 0 {
+				batch = appendBatchTick(batch, message.data)
+				batchTicks += message.ticks
+
+				if batchTicks >= client.batch.maxTicks || len(batch) >= client.batch.maxBytes {
+					if err := flush(); err != nil {
+						return err
+					}
+				} else if deadline == nil {
+					deadline = client.batch.clock.After(client.batch.latency)
+				}
+				continue
+			}
+
+			// Keep the order of the messages, the batched ticks go first
+			if err := flush(); err != nil {
+				return err
+			}
+
+			if err := client.conn.WriteMessage(message.messageType, message.data); err != nil {
+				return err
+			}
+
+		case <-deadline:
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Append the tick of a marshaled tick frame or the ticks of a marshaled tick
+// batch frame to the content of a TickBatch, the tick bytes are copied as they
+// are instead of marshaling them again
+func appendBatchTick(batch []byte, frame []byte) []byte {
+	number, fieldType, length := protowire.ConsumeTag(frame)
+	if length < 0 || (number != tickFieldNumber && number != tickBatchFieldNumber) || fieldType != protowire.BytesType {
+		return batch
+	}
+
+	content, n := protowire.ConsumeBytes(frame[length:])
+	if n < 0 {
+		return batch
+	}
+
+	// The content of a tick batch already is a list of batched ticks
+	if number == tickBatchFieldNumber {
+		return append(batch, content...)
+	}
+
+	batch = protowire.AppendTag(batch, batchTicksFieldNumber, protowire.BytesType)
+	return protowire.AppendBytes(batch, content)
+}
+
+// Queue a message for the client, the message is dropped and false is
+// returned if the queue is full
+func (client *Client) queueMessage(message *OutgoingMessage) bool {
+	select {
+	case client.send <- message:
+		return true
+	default:
+		client.logger.Warn("Dropping message, client send buffer is full")
+		client.metrics.messagesDropped.WithLabelValues(dropSendBufferFull).Inc()
+		return false
+	}
+}
+
+// Marshal and queue a message for the client
+func (client *Client) sendWebSocketMessage(message *pb.WebSocketMessage) {
+	data, err := proto.Marshal(message)
+	if err != nil {
+		client.logger.Error("Error marshaling message", "error", err)
+		return
+	}
+
+	if client.sendBinaryMessage(data) {
+		client.metrics.messageSent(message)
+	}
+
+	if code, failed := webSocketMessageError(message); failed {
+		client.logger.Info("Request failed", "request_id", webSocketMessageRequestID(message), "code", code.String())
+	}
+}
+
+// Queue a text message for the client
+func (client *Client) sendTextMessage(message []byte) {
+	if client.queueMessage(&OutgoingMessage{messageType: websocket.TextMessage, data: message}) {
+		client.metrics.messagesSent.WithLabelValues("text").Inc()
+	}
+}
+
+// Queue a binary message for the client, false is returned if it was dropped
+func (client *Client) sendBinaryMessage(message []byte) bool {
+	return client.queueMessage(&OutgoingMessage{messageType: websocket.BinaryMessage, data: message})
+}
+
+// Queue a marshaled tick frame for the client, it may be sent in a batch
+func (client *Client) sendTickMessage(message []byte) {
+	if client.queueMessage(&OutgoingMessage{messageType: websocket.BinaryMessage, data: message, ticks: 1}) {
+		client.metrics.ticksSent.Inc()
+	}
+}
+
+// Queue a marshaled tick batch frame of the number of ticks for the client,
+// its ticks may be sent in a larger batch, false is returned if it was dropped
+func (client *Client) sendTickBatchMessage(message []byte, ticks int) bool {
+	if !client.queueMessage(&OutgoingMessage{messageType: websocket.BinaryMessage, data: message, ticks: ticks}) {
+		return false
+	}
+
+	client.metrics.ticksSent.Add(float64(ticks))
+
+	return true
+}
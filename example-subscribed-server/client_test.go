@@ -0,0 +1,370 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "handle-subscribed/protobuf"
+	"shared/auth"
+	"shared/ratelimit"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Open a websocket connection to a test HTTP server, the server side of the
+// connection is returned first and the side that dialed second
+func newTestConnPair(t testing.TB) (*websocket.Conn, *websocket.Conn) {
+	t.Helper()
+
+	var upgraded = make(chan *websocket.Conn, 1)
+	upgrader := websocket.Upgrader{}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		upgraded <- conn
+	}))
+	t.Cleanup(testServer.Close)
+
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(testServer.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { peer.Close() })
+
+	conn := <-upgraded
+	t.Cleanup(func() { conn.Close() })
+
+	return conn, peer
+}
+
+// Create a client that writes to the server side of a test connection pair
+// with the batch options and start writing its messages, the peer reads
+// what it writes
+func newWriteTestClient(t testing.TB, batch BatchOptions) (*Client, *websocket.Conn, <-chan error) {
+	t.Helper()
+
+	conn, peer := newTestConnPair(t)
+	client := newClient(conn, &auth.Identity{Subject: "test", Method: "none"}, clientSendBufferSize, batch, newMetrics())
+
+	var done = make(chan error, 1)
+	go func() { done <- client.writeMessages() }()
+
+	return client, peer, done
+}
+
+// Create the marshaled tick frame of the tick with the sequence number
+func testTickFrame(t testing.TB, sequence uint64) []byte {
+	t.Helper()
+
+	frame, err := marshalTick(&pb.Tick{Channel: "prices.btc", Sequence: sequence, Value: &pb.Tick_DoubleValue{DoubleValue: float64(sequence)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return frame
+}
+
+// Read the next frame the client wrote and unmarshal it
+func readTestFrame(t *testing.T, peer *websocket.Conn) *pb.WebSocketMessage {
+	t.Helper()
+
+	peer.SetReadDeadline(time.Now().Add(testReadTimeout))
+	_, data, err := peer.ReadMessage()
+	if err != nil {
+		t.Fatalf("reading frame: %v", err)
+	}
+
+	var message = &pb.WebSocketMessage{}
+	if err := proto.Unmarshal(data, message); err != nil {
+		t.Fatalf("unmarshaling frame: %v", err)
+	}
+
+	return message
+}
+
+// Check that the frame is a batch of the ticks with the sequence numbers
+func checkTickBatch(t *testing.T, message *pb.WebSocketMessage, sequences ...uint64) {
+	t.Helper()
+
+	batch := message.GetTickBatch()
+	if batch == nil {
+		t.Fatalf("got %s, want a tick batch", webSocketMessageType(message))
+	}
+
+	if got := tickSequences(batch.Ticks); !equalSequences(got, sequences) {
+		t.Fatalf("got a batch of the ticks %v, want %v", got, sequences)
+	}
+
+	for _, tick := range batch.Ticks {
+		if tick.Channel != "prices.btc" || tick.GetDoubleValue() != float64(tick.Sequence) {
+			t.Errorf("tick %d was changed by batching: %v", tick.Sequence, tick)
+		}
+	}
+}
+
+func TestAppendBatchTick(t *testing.T) {
+	var batch []byte
+	for sequence := uint64(1); sequence <= 3; sequence++ {
+		batch = appendBatchTick(batch, testTickFrame(t, sequence))
+	}
+
+	// The ticks of a tick batch frame are appended as they are
+	batchFrame, err := marshalTickBatch([]*pb.Tick{
+		{Channel: "prices.btc", Sequence: 4, Value: &pb.Tick_DoubleValue{DoubleValue: 4}},
+		{Channel: "prices.btc", Sequence: 5, Value: &pb.Tick_DoubleValue{DoubleValue: 5}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch = appendBatchTick(batch, batchFrame)
+
+	// A frame that isn't a tick is skipped
+	batch = appendBatchTick(batch, []byte{0xff})
+
+	// The batch is the content of the TickBatch field of a WebSocketMessage
+	frame := protowire.AppendTag(nil, tickBatchFieldNumber, protowire.BytesType)
+	frame = protowire.AppendBytes(frame, batch)
+
+	var message = &pb.WebSocketMessage{}
+	if err := proto.Unmarshal(frame, message); err != nil {
+		t.Fatalf("unmarshaling the batch frame: %v", err)
+	}
+
+	checkTickBatch(t, message, 1, 2, 3, 4, 5)
+}
+
+func TestWriteMessagesBatching(t *testing.T) {
+	clock := newManualClock(clockTestStart)
+	client, peer, done := newWriteTestClient(t, BatchOptions{maxTicks: 3, maxBytes: defaultBatchMaxBytes, latency: time.Second, clock: clock})
+
+	// A full batch is sent right away
+	for sequence := uint64(1); sequence <= 3; sequence++ {
+		client.sendTickMessage(testTickFrame(t, sequence))
+	}
+	checkTickBatch(t, readTestFrame(t, peer), 1, 2, 3)
+
+	// The deadline of the full batch is still on the clock, let it pass so
+	// it isn't counted as the deadline of the next batch
+	clock.advance(time.Second)
+	waitForWaiters(t, clock, 0)
+
+	// A partial batch is sent once its first tick has waited for the latency
+	client.sendTickMessage(testTickFrame(t, 4))
+	client.sendTickMessage(testTickFrame(t, 5))
+	waitForWaiters(t, clock, 1)
+	clock.advance(time.Second)
+	checkTickBatch(t, readTestFrame(t, peer), 4, 5)
+
+	// Another message sends the batched ticks before it to keep the order
+	client.sendTickMessage(testTickFrame(t, 6))
+	client.sendWebSocketMessage(&pb.WebSocketMessage{
+		Paylod: &pb.WebSocketMessage_PublishResponse{PublishResponse: &pb.PublishResponse{RequestId: "1", Success: true}},
+	})
+	checkTickBatch(t, readTestFrame(t, peer), 6)
+	if response := readTestFrame(t, peer).GetPublishResponse(); response == nil || response.RequestId != "1" {
+		t.Fatalf("got %v after the batch, want the publish response", response)
+	}
+
+	// The batched ticks are sent when the client is closed
+	client.sendTickMessage(testTickFrame(t, 7))
+	close(client.send)
+	if err := <-done; err != nil {
+		t.Fatalf("writeMessages: %v", err)
+	}
+	checkTickBatch(t, readTestFrame(t, peer), 7)
+}
+
+func TestWriteMessagesWithoutLatency(t *testing.T) {
+	client, peer, _ := newWriteTestClient(t, BatchOptions{maxTicks: 3, maxBytes: defaultBatchMaxBytes, clock: RealClock{}})
+
+	// Every tick is sent in its own frame
+	for sequence := uint64(1); sequence <= 2; sequence++ {
+		client.sendTickMessage(testTickFrame(t, sequence))
+	}
+
+	for sequence := uint64(1); sequence <= 2; sequence++ {
+		if tick := readTestFrame(t, peer).GetTick(); tick == nil || tick.Sequence != sequence {
+			t.Fatalf("got %v, want the tick %d", tick, sequence)
+		}
+	}
+}
+
+func BenchmarkAppendBatchTick(b *testing.B) {
+	frame := testTickFrame(b, 1)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(frame)))
+
+	var batch []byte
+	for i := 0; i < b.N; i++ {
+		if len(batch) >= defaultBatchMaxBytes {
+			batch = batch[:0]
+		}
+		batch = appendBatchTick(batch, frame)
+	}
+}
+
+func BenchmarkWriteMessages(b *testing.B) {
+	for _, latency := range []time.Duration{0, time.Millisecond} {
+		b.Run(fmt.Sprintf("latency=%s", latency), func(b *testing.B) {
+			client, peer, done := newWriteTestClient(b, BatchOptions{
+				maxTicks: defaultBatchMaxTicks,
+				maxBytes: defaultBatchMaxBytes,
+				latency:  latency,
+				clock:    RealClock{},
+			})
+
+			// Read and drop the frames as fast as they come
+			go func() {
+				for {
+					if _, _, err := peer.NextReader(); err != nil {
+						return
+					}
+				}
+			}()
+
+			frame := testTickFrame(b, 1)
+
+			b.ReportAllocs()
+			b.SetBytes(int64(len(frame)))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				client.send <- &OutgoingMessage{messageType: websocket.BinaryMessage, data: frame, ticks: 1}
+			}
+
+			close(client.send)
+			if err := <-done; err != nil {
+				b.Fatalf("writeMessages: %v", err)
+			}
+		})
+	}
+}
+
+// The number of clients of the concurrency test and how many times each of
+// them connects, changes its subscriptions, publishes and disconnects
+const (
+	churnClients = 8
+	churnRounds  = 10
+)
+
+// Connect to the test server, subscribe, publish and unsubscribe for a round
+// and disconnect without waiting for the answers. It runs in its own
+// goroutine so the errors are returned instead of failing the test
+func churnClient(testServer *httptest.Server, id int) error {
+	url := "ws" + strings.TrimPrefix(testServer.URL, "http") + "/ws"
+
+	for round := 0; round < churnRounds; round++ {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			return fmt.Errorf("client %d round %d: %w", id, round, err)
+		}
+
+		// Read the answers and the ticks until the connection is closed
+		var reading = make(chan struct{})
+		go func() {
+			defer close(reading)
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		requestID := strconv.Itoa(round)
+		for _, message := range []*pb.ClientMessage{
+			{Payload: &pb.ClientMessage_SubscriptionRequest{SubscriptionRequest: &pb.SubscriptionRequest{RequestId: requestID, Action: pb.SubscriptionAction_SUBSCRIBE, Channels: []string{"prices.*"}}}},
+			{Payload: &pb.ClientMessage_PublishRequest{PublishRequest: &pb.PublishRequest{RequestId: requestID, Channel: "prices.eth", Payload: "up"}}},
+			{Payload: &pb.ClientMessage_SubscriptionRequest{SubscriptionRequest: &pb.SubscriptionRequest{RequestId: requestID, Action: pb.SubscriptionAction_UNSUBSCRIBE, Channels: []string{"prices.*"}}}},
+			{Payload: &pb.ClientMessage_SubscriptionRequest{SubscriptionRequest: &pb.SubscriptionRequest{RequestId: requestID, Action: pb.SubscriptionAction_SUBSCRIBE, Channels: []string{"prices.btc"}, Filter: "value > 0"}}},
+			{Payload: &pb.ClientMessage_PublishRequest{PublishRequest: &pb.PublishRequest{RequestId: requestID, Channel: "prices.btc", Payload: "down"}}},
+		} {
+			data, err := proto.Marshal(message)
+			if err != nil {
+				return err
+			}
+
+			if err := conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+				return fmt.Errorf("client %d round %d: %w", id, round, err)
+			}
+		}
+
+		// Disconnect while the server may still be answering
+		conn.Close()
+		<-reading
+	}
+
+	return nil
+}
+
+func TestConcurrentClients(t *testing.T) {
+	server := newTestServer(&Channel{Name: "prices.btc", ClientPublish: true}, &Channel{Name: "prices.eth", ClientPublish: true})
+
+	// Every client connects from the same IP address
+	var options = ratelimit.DefaultOptions
+	options.IPMessageRate = 0
+	options.MaxConnectionsPerIP = 0
+	server.rateLimiter = ratelimit.New(options, time.Now)
+
+	testServer := startTestServer(t, server)
+
+	// Broadcast ticks like a publisher while the clients come and go
+	var stop = make(chan struct{})
+	var broadcasting sync.WaitGroup
+	broadcasting.Add(1)
+	go func() {
+		defer broadcasting.Done()
+		for sequence := 1; ; sequence++ {
+			tick := &pb.Tick{Channel: "prices.btc", Value: &pb.Tick_DoubleValue{DoubleValue: float64(sequence)}}
+			select {
+			case server.broadcast <- []*pb.Tick{tick}:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var clients sync.WaitGroup
+	var errs = make(chan error, churnClients)
+	for id := 0; id < churnClients; id++ {
+		clients.Add(1)
+		go func(id int) {
+			defer clients.Done()
+			errs <- churnClient(testServer, id)
+		}(id)
+	}
+
+	clients.Wait()
+	close(stop)
+	broadcasting.Wait()
+
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Nothing is left of the clients once they are all gone
+	waitForMetric(t, testServer, `subscribed_server_connections`, 0)
+	waitForMetric(t, testServer, `subscribed_server_channel_subscribers{channel="prices.btc"}`, 0)
+	waitForMetric(t, testServer, `subscribed_server_channel_subscribers{channel="prices.eth"}`, 0)
+
+	// The server still delivers to a new subscriber
+	client := dialTestClient(t, testServer)
+	client.subscribe("1", "", "prices.btc")
+	server.broadcast <- []*pb.Tick{{Channel: "prices.btc", Value: &pb.Tick_DoubleValue{DoubleValue: 1}}}
+	if tick := client.readTick(); tick.Channel != "prices.btc" {
+		t.Fatalf("got a tick of %s, want prices.btc", tick.Channel)
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import "time"
+
+// Clock tells the time and waits, the server uses it for tick timestamps,
+// retention and publisher pacing so a test harness can control time
+type Clock interface {
+	Now() time.Time
+	After(duration time.Duration) <-chan time.Time
+}
+
+// RealClock is the wall clock
+type RealClock struct{}
+
+// Get the current time
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// Wait for the duration to pass
+func (RealClock) After(duration time.Duration) <-chan time.Time {
+	return time.After(duration)
+}
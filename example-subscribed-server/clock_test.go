@@ -0,0 +1,154 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	pb "handle-subscribed/protobuf"
+)
+
+// The time the manual clock of the harness tests starts at
+var clockTestStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ExpectedTick is the part of a published tick a harness test checks, the
+// time is the offset from clockTestStart
+type ExpectedTick struct {
+	sequence uint64
+	time     time.Duration
+	units    int64
+}
+
+// Wait until the number of After calls waiting on the clock is count, the
+// publishers are then blocked until the clock is advanced
+func waitForWaiters(t *testing.T, clock *ManualClock, count int) {
+	t.Helper()
+
+	for deadline := time.Now().Add(testReadTimeout); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if clock.waiting() == count {
+			return
+		}
+	}
+
+	t.Fatalf("%d waiters on the clock, want %d", clock.waiting(), count)
+}
+
+func TestManualClockAdvance(t *testing.T) {
+	clock := newManualClock(clockTestStart)
+
+	late := clock.After(3 * time.Second)
+	early := clock.After(time.Second)
+	now := clock.After(0)
+
+	if got := <-now; !got.Equal(clockTestStart) {
+		t.Fatalf("After(0) fired at %s, want the start", got)
+	}
+
+	clock.advance(2 * time.Second)
+	select {
+	case got := <-early:
+		if !got.Equal(clockTestStart.Add(2 * time.Second)) {
+			t.Errorf("early waiter fired at %s, want 2s after the start", got)
+		}
+	default:
+		t.Fatal("the waiter whose deadline passed didn't fire")
+	}
+
+	select {
+	case <-late:
+		t.Fatal("the waiter whose deadline didn't pass fired")
+	default:
+	}
+
+	if got := clock.waiting(); got != 1 {
+		t.Fatalf("%d waiters, want 1", got)
+	}
+
+	clock.advance(time.Second)
+	<-late
+
+	if got := clock.waiting(); got != 0 {
+		t.Fatalf("%d waiters, want 0", got)
+	}
+}
+
+func TestManualClockPublishers(t *testing.T) {
+	btc := &Channel{
+		Name:      "prices.btc",
+		Publisher: randomSource,
+		Source:    &SourceConfig{Type: randomSource, Interval: Duration(time.Second), Start: 50, Step: 5, Min: 10, Max: 100, Scale: 2},
+	}
+	eth := &Channel{
+		Name:      "prices.eth",
+		Publisher: randomSource,
+		Source:    &SourceConfig{Type: randomSource, Interval: Duration(2 * time.Second), Start: 20, Step: 1, Min: 10, Max: 100, Scale: 2},
+	}
+
+	clock := newManualClock(clockTestStart)
+	server := newTestServer(btc, eth)
+	server.clock = clock
+	server.random = rand.New(rand.NewSource(42))
+
+	client := dialTestClient(t, startTestServer(t, server))
+	client.subscribe("1", "", "prices.*")
+
+	// Start the publishers like main does, the seeds are taken in order
+	for _, channel := range []*Channel{btc, eth} {
+		go server.publisher(channel, server.nextSeed())
+	}
+
+	// Each publisher publishes right away and then every interval, the
+	// ticks of a second arrive in any order between the channels
+	var received = make(map[string][]*pb.Tick)
+	for second := 0; second <= 4; second++ {
+		var ticks = 1
+		if second%2 == 0 {
+			ticks = 2
+		}
+
+		for i := 0; i < ticks; i++ {
+			tick := client.readTick()
+			received[tick.Channel] = append(received[tick.Channel], tick)
+		}
+
+		// Advance only once both publishers wait, or a wake up is missed
+		waitForWaiters(t, clock, 2)
+		clock.advance(time.Second)
+	}
+
+	// The values of the random walks seeded from the seed 42
+	want := map[string][]ExpectedTick{
+		"prices.btc": {
+			{sequence: 1, time: 0, units: 5421},
+			{sequence: 2, time: 1 * time.Second, units: 5882},
+			{sequence: 3, time: 2 * time.Second, units: 5913},
+			{sequence: 4, time: 3 * time.Second, units: 5733},
+			{sequence: 5, time: 4 * time.Second, units: 6073},
+		},
+		"prices.eth": {
+			{sequence: 1, time: 0, units: 2025},
+			{sequence: 2, time: 2 * time.Second, units: 1998},
+			{sequence: 3, time: 4 * time.Second, units: 2067},
+		},
+	}
+
+	for channel, wantTicks := range want {
+		ticks := received[channel]
+		if len(ticks) != len(wantTicks) {
+			t.Errorf("%s: got %d ticks, want %d", channel, len(ticks), len(wantTicks))
+			continue
+		}
+
+		for i, tick := range ticks {
+			var got = ExpectedTick{
+				sequence: tick.Sequence,
+				time:     time.Duration(tick.Timestamp - clockTestStart.UnixNano()),
+				units:    tick.GetDecimalValue().GetUnits(),
+			}
+
+			if got != wantTicks[i] || tick.GetDecimalValue().GetScale() != 2 {
+				t.Errorf("%s tick %d: got %+v, want %+v", channel, i, got, wantTicks[i])
+			}
+		}
+	}
+}
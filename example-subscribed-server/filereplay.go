@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "handle-subscribed/protobuf"
+)
+
+// The formats of a recorded file
+const (
+	csvFormat   = "csv"
+	jsonlFormat = "jsonl"
+)
+
+// How long a file replay without an interval waits between the records that
+// have no timestamp and before it starts a looped file over
+const defaultReplayInterval = time.Second
+
+// Record is a recorded value, timestamp is 0 when the recording has none
+type Record struct {
+	value     *pb.Decimal
+	timestamp time.Time
+	metadata  map[string]string
+}
+
+// JSONRecord is a record as a JSON object, the value is a number or a string
+// and the timestamp a RFC 3339 string or unix nanoseconds
+type JSONRecord struct {
+	Value     json.Number       `json:"value"`
+	Timestamp json.RawMessage   `json:"timestamp"`
+	Metadata  map[string]string `json:"metadata"`
+}
+
+// FileReplay publishes the records of a CSV or JSONL file
+type FileReplay struct {
+	clock    Clock
+	path     string
+	format   string
+	interval time.Duration
+	loop     bool
+}
+
+// Create a file replay from the source config that waits between records with the clock
+func newFileReplay(config *SourceConfig, clock Clock) (*FileReplay, error) {
+	if config.Path == "" {
+		return nil, errors.New("file source needs a path")
+	}
+
+	var format = config.Format
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(config.Path), ".")
+	}
+
+	if format != csvFormat && format != jsonlFormat {
+		return nil, fmt.Errorf("unknown file format %q", format)
+	}
+
+	return &FileReplay{
+		clock:    clock,
+		path:     config.Path,
+		format:   format,
+		interval: time.Duration(config.Interval),
+		loop:     config.Loop,
+	}, nil
+}
+
+// Publish the records of the file, without an interval they are spaced by
+// their recorded timestamps or by the default interval when they have none,
+// it returns at the end of the file unless it loops
+func (replay *FileReplay) run(channel string, publish func(tick *pb.Tick)) error {
+	for {
+		if err := replay.replayOnce(channel, publish); err != nil {
+			return err
+		}
+
+		if !replay.loop {
+			return nil
+		}
+
+		// Wait before starting over, a file of a few records with the same
+		// timestamp would otherwise be published in a busy loop
+		var wait = replay.interval
+		if wait <= 0 {
+			wait = defaultReplayInterval
+		}
+		<-replay.clock.After(wait)
+	}
+}
+
+// Publish the records of the file once
+func (replay *FileReplay) replayOnce(channel string, publish func(tick *pb.Tick)) error {
+	file, err := os.Open(replay.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var next func() (*Record, error)
+	if replay.format == csvFormat {
+		next, err = newCSVRecordReader(file)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", replay.path, err)
+		}
+	} else {
+		next = newLineRecordReader(file)
+	}
+
+	// The timestamp of the previous record, the first record is published right away
+	var previous time.Time
+	for first := true; ; first = false {
+		record, err := next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", replay.path, err)
+		}
+
+		// Wait until the record is due
+		if replay.interval > 0 {
+			if !first {
+				<-replay.clock.After(replay.interval)
+			}
+		} else if !record.timestamp.IsZero() {
+			if !previous.IsZero() && record.timestamp.After(previous) {
+				<-replay.clock.After(record.timestamp.Sub(previous))
+			}
+			previous = record.timestamp
+		} else if !first {
+			<-replay.clock.After(defaultReplayInterval)
+		}
+
+		publish(record.tick(channel, fileSource))
+	}
+}
+
+// Create the tick of the record for the channel
+func (record *Record) tick(channel string, source string) *pb.Tick {
+	tick := newTick(channel)
+	tick.Value = &pb.Tick_DecimalValue{DecimalValue: record.value}
+	tick.Metadata = map[string]string{"source": source}
+
+	for key, value := range record.metadata {
+		tick.Metadata[key] = value
+	}
+
+	return tick
+}
+
+// Create a reader of the records of a CSV file, the first row names the
+// columns, value is required, timestamp is optional and the other columns
+// become metadata
+func newCSVRecordReader(reader io.Reader) (func() (*Record, error), error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	var valueColumn, timestampColumn = -1, -1
+	for i, column := range header {
+		switch column {
+		case "value":
+			valueColumn = i
+		case "timestamp":
+			timestampColumn = i
+		}
+	}
+
+	if valueColumn < 0 {
+		return nil, errors.New("csv has no value column")
+	}
+
+	return func() (*Record, error) {
+		row, err := csvReader.Read()
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := parseDecimal(row[valueColumn])
+		if err != nil {
+			return nil, err
+		}
+
+		var record = &Record{value: value, metadata: make(map[string]string)}
+		for i, column := range header {
+			switch i {
+			case valueColumn:
+			case timestampColumn:
+				record.timestamp, err = parseTimestamp(row[i])
+				if err != nil {
+					return nil, err
+				}
+			default:
+				record.metadata[column] = row[i]
+			}
+		}
+
+		return record, nil
+	}, nil
+}
+
+// Create a reader of records with one record per line, a line is either a
+// JSON object or just a number, empty lines are skipped
+func newLineRecordReader(reader io.Reader) func() (*Record, error) {
+	scanner := bufio.NewScanner(reader)
+
+	return func() (*Record, error) {
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" {
+				return parseRecordLine(line)
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+
+		return nil, io.EOF
+	}
+}
+
+// Parse a line that is either a JSON record or just a number
+func parseRecordLine(line string) (*Record, error) {
+	if !strings.HasPrefix(line, "{") {
+		value, err := parseDecimal(line)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Record{value: value}, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+
+	var jsonRecord JSONRecord
+	if err := decoder.Decode(&jsonRecord); err != nil {
+		return nil, err
+	}
+
+	value, err := parseDecimal(jsonRecord.Value.String())
+	if err != nil {
+		return nil, err
+	}
+
+	var record = &Record{value: value, metadata: jsonRecord.Metadata}
+	if len(jsonRecord.Timestamp) > 0 {
+		var text string
+		if err := json.Unmarshal(jsonRecord.Timestamp, &text); err != nil {
+			text = string(jsonRecord.Timestamp)
+		}
+
+		record.timestamp, err = parseTimestamp(text)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return record, nil
+}
+
+// Parse a timestamp that is a RFC 3339 time or unix nanoseconds
+func parseTimestamp(text string) (time.Time, error) {
+	if nanoseconds, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return time.Unix(0, nanoseconds), nil
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, text)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", text)
+	}
+
+	return timestamp, nil
+}
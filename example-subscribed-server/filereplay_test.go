@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "handle-subscribed/protobuf"
+)
+
+// Run a replay of the recording on a manual clock, the published ticks are
+// sent to the returned channel
+func startTestReplay(t *testing.T, recording string, config SourceConfig) (*ManualClock, chan *pb.Tick) {
+	t.Helper()
+
+	config.Path = filepath.Join(t.TempDir(), "recording.csv")
+	if err := os.WriteFile(config.Path, []byte(recording), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	clock := newManualClock(clockTestStart)
+	replay, err := newFileReplay(&config, clock)
+	if err != nil {
+		t.Fatalf("newFileReplay: %v", err)
+	}
+
+	var ticks = make(chan *pb.Tick, 16)
+	go replay.run("recording", func(tick *pb.Tick) { ticks <- tick })
+
+	return clock, ticks
+}
+
+// Check that the next published tick has the value in units and that nothing
+// else is published before the clock is advanced
+func checkReplayTick(t *testing.T, clock *ManualClock, ticks chan *pb.Tick, units int64) {
+	t.Helper()
+
+	select {
+	case tick := <-ticks:
+		if got := tick.GetDecimalValue().GetUnits(); got != units {
+			t.Fatalf("published %d, want %d", got, units)
+		}
+	case <-time.After(testReadTimeout):
+		t.Fatalf("%d wasn't published", units)
+	}
+
+	waitForWaiters(t, clock, 1)
+	select {
+	case tick := <-ticks:
+		t.Fatalf("published %v before the clock was advanced", tick.GetDecimalValue())
+	default:
+	}
+}
+
+func TestFileReplayWithoutTimestamps(t *testing.T) {
+	clock, ticks := startTestReplay(t, "value\n1\n2\n", SourceConfig{Loop: true})
+
+	// The records are a second apart and the loop waits a second to start over
+	checkReplayTick(t, clock, ticks, 1)
+	clock.advance(defaultReplayInterval)
+	checkReplayTick(t, clock, ticks, 2)
+	clock.advance(defaultReplayInterval)
+	checkReplayTick(t, clock, ticks, 1)
+}
+
+func TestFileReplayTimestamps(t *testing.T) {
+	recording := "timestamp,value\n2024-01-01T00:00:00Z,1\n2024-01-01T00:00:05Z,2\n2024-01-01T00:00:05Z,3\n"
+	clock, ticks := startTestReplay(t, recording, SourceConfig{Loop: true})
+
+	// The records are spaced by their timestamps and the loop waits a second
+	checkReplayTick(t, clock, ticks, 1)
+	clock.advance(4 * time.Second)
+	waitForWaiters(t, clock, 1)
+	clock.advance(time.Second)
+
+	// The record with the same timestamp follows right away
+	if tick := <-ticks; tick.GetDecimalValue().GetUnits() != 2 {
+		t.Fatalf("published %v, want 2", tick.GetDecimalValue())
+	}
+	checkReplayTick(t, clock, ticks, 3)
+
+	clock.advance(defaultReplayInterval)
+	checkReplayTick(t, clock, ticks, 1)
+}
+
+func TestFileReplayInterval(t *testing.T) {
+	clock, ticks := startTestReplay(t, "value\n1\n2\n", SourceConfig{Interval: Duration(5 * time.Second), Loop: true})
+
+	checkReplayTick(t, clock, ticks, 1)
+	clock.advance(5 * time.Second)
+	checkReplayTick(t, clock, ticks, 2)
+	clock.advance(5 * time.Second)
+	checkReplayTick(t, clock, ticks, 1)
+}
@@ -0,0 +1,748 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	pb "handle-subscribed/protobuf"
+)
+
+// The longest filter a client can send, it bounds the work done to parse the
+// filter and to evaluate it for every tick
+const maxFilterLength = 256
+
+// Filter is a parsed expression like "value > 50" or "abs(value) < 20" that
+// selects the ticks sent to a subscriber. It can use the fields value, text,
+// channel, sequence and timestamp (in unix seconds) of a tick, metadata.<key>,
+// the functions abs, min, max and contains, arithmetic, comparisons and the
+// logical operators &&, || and !
+type Filter struct {
+	source     string
+	expression FilterExpression
+}
+
+// FilterType is the type of the result of a filter expression
+type FilterType int
+
+// The types a filter expression can have
+const (
+	numberType FilterType = iota
+	stringType
+	boolType
+)
+
+// FilterValue is the result of a filter expression, the field that is used
+// depends on the type of the expression
+type FilterValue struct {
+	number  float64
+	text    string
+	boolean bool
+}
+
+// FilterExpression is a node of a parsed filter, eval returns false when the
+// tick doesn't have a field the expression needs like the value of a text tick
+type FilterExpression interface {
+	valueType() FilterType
+	eval(tick *pb.Tick) (FilterValue, bool)
+}
+
+// Get the name of the type for error messages
+func (filterType FilterType) String() string {
+	switch filterType {
+	case numberType:
+		return "number"
+	case stringType:
+		return "string"
+	default:
+		return "bool"
+	}
+}
+
+// Parse and type check a filter, the filter must be a condition
+func parseFilter(source string) (*Filter, error) {
+	if len(source) > maxFilterLength {
+		return nil, fmt.Errorf("filter is longer than %d characters", maxFilterLength)
+	}
+
+	tokens, err := tokenizeFilter(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var parser = &FilterParser{tokens: tokens}
+	expression, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if token := parser.peek(); token.kind != endToken {
+		return nil, fmt.Errorf("unexpected %q at %d", token.text, token.position)
+	}
+
+	if expression.valueType() != boolType {
+		return nil, fmt.Errorf("filter is a %s, not a condition", expression.valueType())
+	}
+
+	return &Filter{source: source, expression: expression}, nil
+}
+
+// Check if the tick should be sent, a tick without a field the filter needs
+// is not sent
+func (filter *Filter) matches(tick *pb.Tick) bool {
+	result, ok := filter.expression.eval(tick)
+	return ok && result.boolean
+}
+
+// FilterTokenKind is the kind of a token of a filter
+type FilterTokenKind int
+
+// The kinds of token of a filter
+const (
+	endToken FilterTokenKind = iota
+	numberToken
+	stringToken
+	identifierToken
+	operatorToken
+)
+
+// FilterToken is a number, a quoted string, a name or an operator of a filter
+type FilterToken struct {
+	kind     FilterTokenKind
+	text     string
+	number   float64
+	position int
+}
+
+// The operators of a filter, the two character ones come first so they are
+// matched before their one character prefix
+var filterOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")", ",", "."}
+
+// Split the filter into tokens, it always ends with an end token
+func tokenizeFilter(source string) ([]FilterToken, error) {
+	var tokens []FilterToken
+	var position = 0
+
+next:
+	for position < len(source) {
+		var char = rune(source[position])
+		switch {
+		case unicode.IsSpace(char):
+			position++
+
+		case unicode.IsDigit(char):
+			var end = position
+			for end < len(source) && (unicode.IsDigit(rune(source[end])) || source[end] == '.') {
+				end++
+			}
+
+			number, err := strconv.ParseFloat(source[position:end], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at %d", source[position:end], position)
+			}
+
+			tokens = append(tokens, FilterToken{kind: numberToken, text: source[position:end], number: number, position: position})
+			position = end
+
+		case char == '"':
+			var end = position + 1
+			for end < len(source) && source[end] != '"' {
+				end++
+			}
+
+			if end == len(source) {
+				return nil, fmt.Errorf("unterminated string at %d", position)
+			}
+
+			tokens = append(tokens, FilterToken{kind: stringToken, text: source[position+1 : end], position: position})
+			position = end + 1
+
+		case unicode.IsLetter(char) || char == '_':
+			var end = position
+			for end < len(source) && (unicode.IsLetter(rune(source[end])) || unicode.IsDigit(rune(source[end])) || source[end] == '_') {
+				end++
+			}
+
+			tokens = append(tokens, FilterToken{kind: identifierToken, text: source[position:end], position: position})
+			position = end
+
+		default:
+			for _, operator := range filterOperators {
+				if strings.HasPrefix(source[position:], operator) {
+					tokens = append(tokens, FilterToken{kind: operatorToken, text: operator, position: position})
+					position += len(operator)
+					continue next
+				}
+			}
+
+			return nil, fmt.Errorf("unexpected %q at %d", char, position)
+		}
+	}
+
+	return append(tokens, FilterToken{kind: endToken, text: "end of filter", position: len(source)}), nil
+}
+
+// FilterParser builds the expression of a filter from its tokens by recursive
+// descent, each method parses one level of operator precedence
+type FilterParser struct {
+	tokens   []FilterToken
+	position int
+}
+
+// Get the next token without consuming it
+func (parser *FilterParser) peek() FilterToken {
+	return parser.tokens[parser.position]
+}
+
+// Consume the next token if it is the operator
+func (parser *FilterParser) accept(operator string) bool {
+	if token := parser.peek(); token.kind == operatorToken && token.text == operator {
+		parser.position++
+		return true
+	}
+
+	return false
+}
+
+// Consume the next token, it must be the operator
+func (parser *FilterParser) expect(operator string) error {
+	if !parser.accept(operator) {
+		token := parser.peek()
+		return fmt.Errorf("expected %q at %d, got %q", operator, token.position, token.text)
+	}
+
+	return nil
+}
+
+// Parse a chain of conditions joined by ||
+func (parser *FilterParser) parseOr() (FilterExpression, error) {
+	left, err := parser.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for parser.accept("||") {
+		right, err := parser.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		if left, err = newBinaryExpression("||", left, right); err != nil {
+			return nil, err
+		}
+	}
+
+	return left, nil
+}
+
+// Parse a chain of conditions joined by &&
+func (parser *FilterParser) parseAnd() (FilterExpression, error) {
+	left, err := parser.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+
+	for parser.accept("&&") {
+		right, err := parser.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+
+		if left, err = newBinaryExpression("&&", left, right); err != nil {
+			return nil, err
+		}
+	}
+
+	return left, nil
+}
+
+// Parse a comparison of two values, comparisons can't be chained
+func (parser *FilterParser) parseComparison() (FilterExpression, error) {
+	left, err := parser.parseSum()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, operator := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if parser.accept(operator) {
+			right, err := parser.parseSum()
+			if err != nil {
+				return nil, err
+			}
+
+			return newBinaryExpression(operator, left, right)
+		}
+	}
+
+	return left, nil
+}
+
+// Parse a chain of additions and subtractions
+func (parser *FilterParser) parseSum() (FilterExpression, error) {
+	left, err := parser.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		var operator string
+		switch {
+		case parser.accept("+"):
+			operator = "+"
+		case parser.accept("-"):
+			operator = "-"
+		default:
+			return left, nil
+		}
+
+		right, err := parser.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+
+		if left, err = newBinaryExpression(operator, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Parse a chain of multiplications, divisions and remainders
+func (parser *FilterParser) parseProduct() (FilterExpression, error) {
+	left, err := parser.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		var operator string
+		switch {
+		case parser.accept("*"):
+			operator = "*"
+		case parser.accept("/"):
+			operator = "/"
+		case parser.accept("%"):
+			operator = "%"
+		default:
+			return left, nil
+		}
+
+		right, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		if left, err = newBinaryExpression(operator, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Parse a negation or a negative number
+func (parser *FilterParser) parseUnary() (FilterExpression, error) {
+	for _, operator := range []string{"!", "-"} {
+		if parser.accept(operator) {
+			operand, err := parser.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+
+			return newUnaryExpression(operator, operand)
+		}
+	}
+
+	return parser.parsePrimary()
+}
+
+// Parse a literal, a field, a function call or an expression in parentheses
+func (parser *FilterParser) parsePrimary() (FilterExpression, error) {
+	var token = parser.peek()
+	parser.position++
+
+	switch token.kind {
+	case numberToken:
+		return &LiteralExpression{kind: numberType, value: FilterValue{number: token.number}}, nil
+
+	case stringToken:
+		return &LiteralExpression{kind: stringType, value: FilterValue{text: token.text}}, nil
+
+	case identifierToken:
+		switch token.text {
+		case "true", "false":
+			return &LiteralExpression{kind: boolType, value: FilterValue{boolean: token.text == "true"}}, nil
+
+		case "metadata":
+			if err := parser.expect("."); err != nil {
+				return nil, err
+			}
+
+			key := parser.peek()
+			if key.kind != identifierToken {
+				return nil, fmt.Errorf("expected a metadata key at %d", key.position)
+			}
+			parser.position++
+
+			return &MetadataExpression{key: key.text}, nil
+		}
+
+		if parser.accept("(") {
+			return parser.parseCall(token)
+		}
+
+		if kind, ok := filterFields[token.text]; ok {
+			return &FieldExpression{name: token.text, kind: kind}, nil
+		}
+
+		return nil, fmt.Errorf("unknown field %q at %d", token.text, token.position)
+
+	case operatorToken:
+		if token.text == "(" {
+			expression, err := parser.parseOr()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := parser.expect(")"); err != nil {
+				return nil, err
+			}
+
+			return expression, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected %q at %d", token.text, token.position)
+}
+
+// Parse the arguments of a function call after its opening parenthesis
+func (parser *FilterParser) parseCall(name FilterToken) (FilterExpression, error) {
+	var args []FilterExpression
+	if !parser.accept(")") {
+		for {
+			arg, err := parser.parseOr()
+			if err != nil {
+				return nil, err
+			}
+
+			args = append(args, arg)
+			if parser.accept(")") {
+				break
+			}
+
+			if err := parser.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return newCallExpression(name, args)
+}
+
+// LiteralExpression is a number, a quoted string, true or false
+type LiteralExpression struct {
+	kind  FilterType
+	value FilterValue
+}
+
+// Get the type of the literal
+func (literal *LiteralExpression) valueType() FilterType {
+	return literal.kind
+}
+
+// Get the literal value
+func (literal *LiteralExpression) eval(tick *pb.Tick) (FilterValue, bool) {
+	return literal.value, true
+}
+
+// The fields of a tick a filter can use and their types
+var filterFields = map[string]FilterType{
+	"value":     numberType,
+	"text":      stringType,
+	"channel":   stringType,
+	"sequence":  numberType,
+	"timestamp": numberType,
+}
+
+// FieldExpression is a field of the tick
+type FieldExpression struct {
+	name string
+	kind FilterType
+}
+
+// Get the type of the field
+func (field *FieldExpression) valueType() FilterType {
+	return field.kind
+}
+
+// Get the field of the tick
+func (field *FieldExpression) eval(tick *pb.Tick) (FilterValue, bool) {
+	switch field.name {
+	case "value":
+		switch value := tick.GetValue().(type) {
+		case *pb.Tick_DoubleValue:
+			return FilterValue{number: value.DoubleValue}, true
+		case *pb.Tick_DecimalValue:
+			return FilterValue{number: float64(value.DecimalValue.Units) / math.Pow10(int(value.DecimalValue.Scale))}, true
+		default:
+			return FilterValue{}, false
+		}
+
+	case "text":
+		value, ok := tick.GetValue().(*pb.Tick_Text)
+		if !ok {
+			return FilterValue{}, false
+		}
+		return FilterValue{text: value.Text}, true
+
+	case "channel":
+		return FilterValue{text: tick.Channel}, true
+
+	case "sequence":
+		return FilterValue{number: float64(tick.Sequence)}, true
+
+	default:
+		return FilterValue{number: float64(tick.Timestamp) / 1e9}, true
+	}
+}
+
+// MetadataExpression is the metadata value of the tick with the key
+type MetadataExpression struct {
+	key string
+}
+
+// Get the type of the metadata value, a string
+func (metadata *MetadataExpression) valueType() FilterType {
+	return stringType
+}
+
+// Get the metadata value of the tick
+func (metadata *MetadataExpression) eval(tick *pb.Tick) (FilterValue, bool) {
+	value, ok := tick.Metadata[metadata.key]
+	return FilterValue{text: value}, ok
+}
+
+// UnaryExpression is a negated condition or a negated number
+type UnaryExpression struct {
+	operator string
+	operand  FilterExpression
+}
+
+// Type check a unary operator
+func newUnaryExpression(operator string, operand FilterExpression) (FilterExpression, error) {
+	var want = numberType
+	if operator == "!" {
+		want = boolType
+	}
+
+	if operand.valueType() != want {
+		return nil, fmt.Errorf("%s needs a %s, got a %s", operator, want, operand.valueType())
+	}
+
+	return &UnaryExpression{operator: operator, operand: operand}, nil
+}
+
+// Get the type of the operand
+func (unary *UnaryExpression) valueType() FilterType {
+	return unary.operand.valueType()
+}
+
+// Apply the operator to the operand
+func (unary *UnaryExpression) eval(tick *pb.Tick) (FilterValue, bool) {
+	value, ok := unary.operand.eval(tick)
+	if !ok {
+		return FilterValue{}, false
+	}
+
+	if unary.operator == "!" {
+		return FilterValue{boolean: !value.boolean}, true
+	}
+
+	return FilterValue{number: -value.number}, true
+}
+
+// BinaryExpression is an arithmetic, comparison or logical operator
+type BinaryExpression struct {
+	operator string
+	kind     FilterType
+	left     FilterExpression
+	right    FilterExpression
+}
+
+// Type check a binary operator, numbers and strings can be compared with
+// each other and any values of the same type can be checked for equality
+func newBinaryExpression(operator string, left FilterExpression, right FilterExpression) (FilterExpression, error) {
+	var leftType, rightType = left.valueType(), right.valueType()
+
+	var kind FilterType
+	var ok bool
+	switch operator {
+	case "&&", "||":
+		kind, ok = boolType, leftType == boolType && rightType == boolType
+	case "==", "!=":
+		kind, ok = boolType, leftType == rightType
+	case "<", "<=", ">", ">=":
+		kind, ok = boolType, leftType == rightType && leftType != boolType
+	default:
+		kind, ok = numberType, leftType == numberType && rightType == numberType
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("%s can't be used with a %s and a %s", operator, leftType, rightType)
+	}
+
+	return &BinaryExpression{operator: operator, kind: kind, left: left, right: right}, nil
+}
+
+// Get the type of the result of the operator
+func (binary *BinaryExpression) valueType() FilterType {
+	return binary.kind
+}
+
+// Apply the operator to both sides
+func (binary *BinaryExpression) eval(tick *pb.Tick) (FilterValue, bool) {
+	left, leftOk := binary.left.eval(tick)
+
+	// A side of && or || that decides the result on its own is enough, so
+	// "text == \"buy\" || value > 50" works for text and number ticks
+	if binary.operator == "&&" || binary.operator == "||" {
+		var decided = binary.operator == "||"
+		if leftOk && left.boolean == decided {
+			return FilterValue{boolean: decided}, true
+		}
+
+		right, rightOk := binary.right.eval(tick)
+		if rightOk && (right.boolean == decided || leftOk) {
+			return FilterValue{boolean: right.boolean}, true
+		}
+
+		return FilterValue{}, false
+	}
+
+	right, rightOk := binary.right.eval(tick)
+	if !leftOk || !rightOk {
+		return FilterValue{}, false
+	}
+
+	switch binary.operator {
+	case "==":
+		return FilterValue{boolean: left == right}, true
+	case "!=":
+		return FilterValue{boolean: left != right}, true
+	case "<", "<=", ">", ">=":
+		var order int
+		if binary.left.valueType() == stringType {
+			order = strings.Compare(left.text, right.text)
+		} else if left.number < right.number {
+			order = -1
+		} else if left.number > right.number {
+			order = 1
+		}
+
+		switch binary.operator {
+		case "<":
+			return FilterValue{boolean: order < 0}, true
+		case "<=":
+			return FilterValue{boolean: order <= 0}, true
+		case ">":
+			return FilterValue{boolean: order > 0}, true
+		default:
+			return FilterValue{boolean: order >= 0}, true
+		}
+	case "+":
+		return FilterValue{number: left.number + right.number}, true
+	case "-":
+		return FilterValue{number: left.number - right.number}, true
+	case "*":
+		return FilterValue{number: left.number * right.number}, true
+	}
+
+	// A division by zero has no value
+	if right.number == 0 {
+		return FilterValue{}, false
+	}
+
+	if binary.operator == "/" {
+		return FilterValue{number: left.number / right.number}, true
+	}
+
+	return FilterValue{number: math.Mod(left.number, right.number)}, true
+}
+
+// CallExpression is a call of one of the filter functions
+type CallExpression struct {
+	function string
+	args     []FilterExpression
+}
+
+// Type check a function call, abs takes a number, min and max take one or
+// more numbers and contains takes two strings
+func newCallExpression(name FilterToken, args []FilterExpression) (FilterExpression, error) {
+	var want []FilterType
+	switch name.text {
+	case "abs":
+		want = []FilterType{numberType}
+	case "min", "max":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%s needs at least one number", name.text)
+		}
+		for range args {
+			want = append(want, numberType)
+		}
+	case "contains":
+		want = []FilterType{stringType, stringType}
+	default:
+		return nil, fmt.Errorf("unknown function %q at %d", name.text, name.position)
+	}
+
+	if len(args) != len(want) {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d", name.text, len(want), len(args))
+	}
+
+	for i, arg := range args {
+		if arg.valueType() != want[i] {
+			return nil, fmt.Errorf("argument %d of %s must be a %s, got a %s", i+1, name.text, want[i], arg.valueType())
+		}
+	}
+
+	return &CallExpression{function: name.text, args: args}, nil
+}
+
+// Get the type of the result of the function
+func (call *CallExpression) valueType() FilterType {
+	if call.function == "contains" {
+		return boolType
+	}
+
+	return numberType
+}
+
+// Call the function with the arguments
+func (call *CallExpression) eval(tick *pb.Tick) (FilterValue, bool) {
+	var values []FilterValue
+	for _, arg := range call.args {
+		value, ok := arg.eval(tick)
+		if !ok {
+			return FilterValue{}, false
+		}
+
+		values = append(values, value)
+	}
+
+	switch call.function {
+	case "abs":
+		return FilterValue{number: math.Abs(values[0].number)}, true
+	case "contains":
+		return FilterValue{boolean: strings.Contains(values[0].text, values[1].text)}, true
+	}
+
+	var result = values[0].number
+	for _, value := range values[1:] {
+		if call.function == "min" {
+			result = math.Min(result, value.number)
+		} else {
+			result = math.Max(result, value.number)
+		}
+	}
+
+	return FilterValue{number: result}, true
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	pb "handle-subscribed/protobuf"
+)
+
+// Create a tick with a number value
+func numberTick(value float64) *pb.Tick {
+	return &pb.Tick{Channel: "prices.btc", Sequence: 7, Timestamp: 1700000000e9, Value: &pb.Tick_DoubleValue{DoubleValue: value}}
+}
+
+// Create a tick with a text value
+func textTick(text string) *pb.Tick {
+	return &pb.Tick{Channel: "orders", Sequence: 3, Timestamp: 1700000000e9, Value: &pb.Tick_Text{Text: text}}
+}
+
+// Create a tick with a text value and metadata
+func metadataTick(text string, metadata map[string]string) *pb.Tick {
+	tick := textTick(text)
+	tick.Metadata = metadata
+
+	return tick
+}
+
+func TestFilterMatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		tick   *pb.Tick
+		want   bool
+	}{
+		// Precedence and associativity
+		{"product before sum", "1 + 2 * 3 == 7", numberTick(0), true},
+		{"parentheses", "(1 + 2) * 3 == 9", numberTick(0), true},
+		{"left associative minus", "10 - 4 - 3 == 3", numberTick(0), true},
+		{"left associative division", "8 / 4 / 2 == 1", numberTick(0), true},
+		{"remainder", "7 % 4 == 3", numberTick(0), true},
+		{"unary minus", "-2 * 3 == -6", numberTick(0), true},
+		{"double negation", "--value == 5", numberTick(5), true},
+		{"and before or", "true || false && false", numberTick(0), true},
+		{"parenthesized or", "(true || false) && false", numberTick(0), false},
+		{"not before and", "!false && true", numberTick(0), true},
+		{"comparison before and", "value > 1 && value < 10", numberTick(5), true},
+		{"arithmetic before comparison", "value * 2 > 9", numberTick(5), true},
+
+		// Fields and functions
+		{"value", "value >= 50", numberTick(50), true},
+		{"decimal value", "value == 12.5", &pb.Tick{Value: &pb.Tick_DecimalValue{DecimalValue: &pb.Decimal{Units: 1250, Scale: 2}}}, true},
+		{"text", `text == "buy"`, textTick("buy"), true},
+		{"channel", `channel == "prices.btc"`, numberTick(1), true},
+		{"sequence", "sequence == 7", numberTick(1), true},
+		{"timestamp in seconds", "timestamp == 1700000000", numberTick(1), true},
+		{"string order", `text < "c"`, textTick("buy"), true},
+		{"abs", "abs(value) < 20", numberTick(-15), true},
+		{"min", "min(value, 3, 9) == 3", numberTick(5), true},
+		{"max", "max(value, 3, 9) == 9", numberTick(5), true},
+		{"contains", `contains(channel, "btc")`, numberTick(1), true},
+
+		// A division by zero has no value, so only a side of && or || that
+		// doesn't need it can match
+		{"division by zero", "value / 0 > 1", numberTick(5), false},
+		{"negated division by zero", "!(value / 0 > 1)", numberTick(5), false},
+		{"remainder by zero", "value % 0 == 0", numberTick(5), false},
+		{"division by zero or true", "value / 0 > 1 || true", numberTick(5), true},
+		{"division by zero and false", "value / 0 > 1 && false", numberTick(5), false},
+		{"division by zero field", "10 / value > 1", numberTick(0), false},
+
+		// A tick without a field the filter needs doesn't match unless the
+		// other side of && or || decides the result
+		{"or text side on text tick", `text == "buy" || value > 50`, textTick("buy"), true},
+		{"or value side on number tick", `text == "buy" || value > 50`, numberTick(60), true},
+		{"or value side false on number tick", `text == "buy" || value > 50`, numberTick(40), false},
+		{"or text side false on text tick", `text == "buy" || value > 50`, textTick("sell"), false},
+		{"and missing value on text tick", `text == "buy" && value > 50`, textTick("buy"), false},
+		{"and missing text on number tick", `text == "buy" && value > 50`, numberTick(60), false},
+		{"negated and decided by text", `!(text == "buy" && value > 50)`, textTick("sell"), true},
+		{"negated and undecided", `!(text == "sell" && value > 50)`, textTick("sell"), false},
+		{"negated or undecided", `!(text == "buy" || value > 50)`, numberTick(40), false},
+		{"value on text tick", "value > 0", textTick("buy"), false},
+		{"text on number tick", `text != "buy"`, numberTick(1), false},
+
+		// Metadata
+		{"metadata", `metadata.side == "buy"`, metadataTick("x", map[string]string{"side": "buy"}), true},
+		{"metadata other value", `metadata.side == "buy"`, metadataTick("x", map[string]string{"side": "sell"}), false},
+		{"missing metadata", `metadata.side == "buy"`, metadataTick("x", nil), false},
+		{"negated missing metadata", `!(metadata.side == "buy")`, metadataTick("x", nil), false},
+		{"missing metadata or true", `metadata.side == "buy" || true`, metadataTick("x", nil), true},
+		{"metadata contains", `contains(metadata.exchange, "bin")`, metadataTick("x", map[string]string{"exchange": "binance"}), true},
+		{"metadata and text", `metadata.side == "buy" && text == "x"`, metadataTick("x", map[string]string{"side": "buy"}), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := parseFilter(test.filter)
+			if err != nil {
+				t.Fatalf("parseFilter(%q): %v", test.filter, err)
+			}
+
+			if got := filter.matches(test.tick); got != test.want {
+				t.Errorf("%q matches %v = %v, want %v", test.filter, test.tick, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		err    string
+	}{
+		// Type errors
+		{"number and string", "value + text > 1", "+ can't be used with a number and a string"},
+		{"string and number", `text > 5`, "> can't be used with a string and a number"},
+		{"bools have no order", "true < false", "< can't be used with a bool and a bool"},
+		{"and of numbers", "value && true", "&& can't be used with a number and a bool"},
+		{"not a condition", "value + 1", "filter is a number, not a condition"},
+		{"string is not a condition", "text", "filter is a string, not a condition"},
+		{"not of a number", "!value", "! needs a bool, got a number"},
+		{"minus of a string", "-text == 1", "- needs a number, got a string"},
+		{"abs of a string", "abs(text) > 1", "argument 1 of abs must be a number, got a string"},
+		{"contains of a number", `contains(value, "a")`, "argument 1 of contains must be a string, got a number"},
+		{"metadata is a string", "metadata.size > 5", "> can't be used with a string and a number"},
+
+		// Calls
+		{"min without arguments", "min() > 1", "min needs at least one number"},
+		{"abs with two arguments", "abs(1, 2) > 1", "abs takes 1 arguments, got 2"},
+		{"unknown function", "foo(1) > 1", `unknown function "foo" at 0`},
+
+		// Syntax
+		{"unknown field", "price > 1", `unknown field "price" at 0`},
+		{"missing operand", "value >", `unexpected "end of filter" at 7`},
+		{"chained comparison", "1 < 2 < 3", `unexpected "<" at 6`},
+		{"trailing token", "value > 1 1", `unexpected "1" at 10`},
+		{"unterminated string", `text == "buy`, "unterminated string at 8"},
+		{"unclosed parenthesis", "(value > 1", `expected ")" at 10, got "end of filter"`},
+		{"invalid number", "value > 1.2.3", `invalid number "1.2.3" at 8`},
+		{"unknown character", "value @ 1", `unexpected '@' at 6`},
+		{"metadata without key", "metadata. == 1", "expected a metadata key at 10"},
+		{"metadata without dot", `metadata == "a"`, `expected "." at 9, got "=="`},
+		{"empty", "", `unexpected "end of filter" at 0`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseFilter(test.filter)
+			if err == nil {
+				t.Fatalf("parseFilter(%q) succeeded, want %q", test.filter, test.err)
+			}
+
+			if err.Error() != test.err {
+				t.Errorf("parseFilter(%q) = %q, want %q", test.filter, err, test.err)
+			}
+		})
+	}
+}
+
+func TestParseFilterMaxLength(t *testing.T) {
+	// Pad a valid filter with spaces up to the limit
+	filter := "value > 1" + strings.Repeat(" ", maxFilterLength-len("value > 1"))
+	if _, err := parseFilter(filter); err != nil {
+		t.Fatalf("filter of %d characters: %v", len(filter), err)
+	}
+
+	filter += " "
+	_, err := parseFilter(filter)
+	if err == nil || err.Error() != "filter is longer than 256 characters" {
+		t.Fatalf("filter of %d characters: got %v, want it to be too long", len(filter), err)
+	}
+}
+
+func TestInvalidFilterErrorMessage(t *testing.T) {
+	server := newTestServer(&Channel{Name: "prices"})
+	client := dialTestClient(t, startTestServer(t, server))
+
+	client.send(&pb.ClientMessage{
+		Payload: &pb.ClientMessage_SubscriptionRequest{
+			SubscriptionRequest: &pb.SubscriptionRequest{
+				RequestId: "42",
+				Action:    pb.SubscriptionAction_SUBSCRIBE,
+				Channels:  []string{"prices"},
+				Filter:    "value > ",
+			},
+		},
+	})
+
+	errorMessage := client.read().GetErrorMessage()
+	if errorMessage == nil {
+		t.Fatal("an invalid filter wasn't answered with an error message")
+	}
+
+	if errorMessage.RequestId != "42" || errorMessage.ErrorCode != pb.ErrorCode_INVALID_REQUEST {
+		t.Errorf("got request id %q and code %s, want 42 and INVALID_REQUEST", errorMessage.RequestId, errorMessage.ErrorCode)
+	}
+
+	if !strings.HasPrefix(errorMessage.ErrorMessage, "invalid filter: ") {
+		t.Errorf("got error message %q, want an invalid filter", errorMessage.ErrorMessage)
+	}
+
+	// The rejected request didn't subscribe the client, a valid one still works
+	response := client.subscribe("43", "value > 1", "prices")
+	if len(response.Channels) != 1 || response.Channels[0] != "prices" {
+		t.Errorf("subscribed to %v after the valid filter, want [prices]", response.Channels)
+	}
+}
@@ -1,10 +1,24 @@
 module handle-subscribed
 
-go 1.19
+go 1.21
 
 require (
 	github.com/golang/protobuf v1.5.2
 	github.com/gorilla/websocket v1.5.0
+	github.com/prometheus/client_golang v1.9.0
+	shared v0.0.0
 )
 
 require google.golang.org/protobuf v1.26.0
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.1.1 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
+	github.com/prometheus/client_model v0.2.0 // indirect
+	github.com/prometheus/common v0.15.0 // indirect
+	github.com/prometheus/procfs v0.2.0 // indirect
+	golang.org/x/sys v0.14.0 // indirect
+)
+
+replace shared => ../shared
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"time"
+
+	pb "handle-subscribed/protobuf"
+)
+
+// The number of ticks and how long they are kept per channel for clients that
+// need to resync or replay, by default ticks are only dropped by count
+const (
+	defaultHistorySize = 1000
+	defaultHistoryAge  = 0
+)
+
+// The number of ticks sent in one frame when a history is replayed
+const replayBatchSize = 100
+
+// ChannelHistory holds the sequence number of the last tick published to a
+// channel and a ring buffer of the last ticks
+type ChannelHistory struct {
+	lastSequence uint64
+	ticks        []*pb.Tick
+	start        int
+	count        int
+	maxAge       time.Duration
+	clock        Clock
+}
+
+// Create a new empty channel history that keeps up to size ticks, ticks
+// older than maxAge by the clock are dropped as well unless maxAge is 0
+func newChannelHistory(size int, maxAge time.Duration, clock Clock) *ChannelHistory {
+	if size < 0 {
+		size = 0
+	}
+
+	return &ChannelHistory{
+		ticks:  make([]*pb.Tick, size),
+		maxAge: maxAge,
+		clock:  clock,
+	}
+}
+
+// Stamp the next sequence number on the tick and keep it, the oldest tick is
+// dropped when the buffer is full
+func (history *ChannelHistory) add(tick *pb.Tick) {
+	history.lastSequence++
+	tick.Sequence = history.lastSequence
+
+	history.expire()
+
+	if len(history.ticks) == 0 {
+		return
+	}
+
+	if history.count < len(history.ticks) {
+		history.ticks[(history.start+history.count)%len(history.ticks)] = tick
+		history.count++
+		return
+	}
+
+	history.ticks[history.start] = tick
+	history.start = (history.start + 1) % len(history.ticks)
+}
+
+// Drop the ticks that are older than the maximum age from the front of the buffer
+func (history *ChannelHistory) expire() {
+	if history.maxAge <= 0 {
+		return
+	}
+
+	var cutoff = history.clock.Now().Add(-history.maxAge).UnixNano()
+	for history.count > 0 && history.ticks[history.start].Timestamp < cutoff {
+		history.ticks[history.start] = nil
+		history.start = (history.start + 1) % len(history.ticks)
+		history.count--
+	}
+}
+
+// Get the kept ticks selected by the replay options
+func (history *ChannelHistory) replay(options *pb.ReplayOptions) []*pb.Tick {
+	history.expire()
+
+	switch from := options.GetFrom().(type) {
+	case *pb.ReplayOptions_FromSequence:
+		ticks, _ := history.between(from.FromSequence, 0)
+		return ticks
+
+	case *pb.ReplayOptions_FromTimestamp:
+		ticks, _ := history.between(0, 0)
+		for i, tick := range ticks {
+			if tick.Timestamp >= from.FromTimestamp {
+				return ticks[i:]
+			}
+		}
+		return nil
+
+	case *pb.ReplayOptions_LastValue:
+		if !from.LastValue || history.count == 0 {
+			return nil
+		}
+		return []*pb.Tick{history.ticks[(history.start+history.count-1)%len(history.ticks)]}
+
+	default:
+		return nil
+	}
+}
+
+// Get the kept ticks with a sequence number from from to to inclusive, the
+// second return value is false if some of them are no longer kept
+func (history *ChannelHistory) between(from uint64, to uint64) ([]*pb.Tick, bool) {
+	history.expire()
+
+	if to == 0 || to > history.lastSequence {
+		to = history.lastSequence
+	}
+
+	if from == 0 {
+		from = 1
+	}
+
+	if from > to {
+		return nil, true
+	}
+
+	// The kept ticks have consecutive sequence numbers starting at the oldest one
+	var oldest = history.lastSequence - uint64(history.count) + 1
+	var complete = from >= oldest
+	if !complete {
+		from = oldest
+	}
+
+	var ticks []*pb.Tick
+	for sequence := from; sequence <= to; sequence++ {
+		index := (history.start + int(sequence-oldest)) % len(history.ticks)
+		ticks = append(ticks, history.ticks[index])
+	}
+
+	return ticks, complete
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	pb "handle-subscribed/protobuf"
+)
+
+func TestReplayLongerThanSendBuffer(t *testing.T) {
+	server := newTestServer(&Channel{Name: "prices.btc"})
+	client := dialTestClient(t, startTestServer(t, server))
+
+	// Publish more ticks than the send queue of a client holds
+	const published = 2*clientSendBufferSize + 50
+
+	var ticks []*pb.Tick
+	for i := 0; i < published; i++ {
+		tick := newTick("prices.btc")
+		tick.Value = &pb.Tick_DoubleValue{DoubleValue: float64(i)}
+		ticks = append(ticks, tick)
+	}
+	server.broadcast <- ticks
+
+	client.send(&pb.ClientMessage{
+		Payload: &pb.ClientMessage_SubscriptionRequest{
+			SubscriptionRequest: &pb.SubscriptionRequest{
+				RequestId: "1",
+				Action:    pb.SubscriptionAction_SUBSCRIBE,
+				Channels:  []string{"prices.btc"},
+				Replay:    &pb.ReplayOptions{From: &pb.ReplayOptions_FromSequence{FromSequence: 1}},
+			},
+		},
+	})
+
+	if response := client.read().GetSubscriptionResponse(); response == nil || !response.Success {
+		t.Fatalf("got %v, want a successful subscription response", response)
+	}
+
+	// Every replayed tick arrives in order, batched in frames
+	var frames int
+	var replayed []*pb.Tick
+	for len(replayed) < published {
+		batch := client.read().GetTickBatch()
+		if batch == nil {
+			t.Fatalf("got a frame that isn't a tick batch after %d ticks", len(replayed))
+		}
+
+		frames++
+		replayed = append(replayed, batch.Ticks...)
+	}
+
+	if len(replayed) != published {
+		t.Fatalf("got %d replayed ticks, want %d", len(replayed), published)
+	}
+
+	for i, tick := range replayed {
+		if tick.Sequence != uint64(i+1) || !tick.Replayed {
+			t.Fatalf("replayed tick %d is %v, want the sequence %d marked as replayed", i, tick, i+1)
+		}
+	}
+
+	if want := (published + replayBatchSize - 1) / replayBatchSize; frames != want {
+		t.Errorf("got the replay in %d frames, want %d", frames, want)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	pb "handle-subscribed/protobuf"
+)
+
+// LineReader publishes the records written to stdin or a named pipe, one per
+// line as a JSON object or just a number
+type LineReader struct {
+	source string
+	path   string
+}
+
+// Create a line reader from the source config
+func newLineReader(config *SourceConfig) (*LineReader, error) {
+	if config.Type == pipeSource && config.Path == "" {
+		return nil, errors.New("pipe source needs a path")
+	}
+
+	return &LineReader{source: config.Type, path: config.Path}, nil
+}
+
+// Publish a tick for every line, stdin is read until it is closed while a
+// named pipe is opened again every time its writer closes it
+func (reader *LineReader) run(channel string, publish func(tick *pb.Tick)) error {
+	if reader.source == stdinSource {
+		return reader.readLines(os.Stdin, channel, publish)
+	}
+
+	for {
+		// Opening a named pipe blocks until there is a writer
+		file, err := os.Open(reader.path)
+		if err != nil {
+			return err
+		}
+
+		err = reader.readLines(file, channel, publish)
+		file.Close()
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Publish a tick for every line until the end of the input, invalid lines are
+// skipped
+func (reader *LineReader) readLines(input io.Reader, channel string, publish func(tick *pb.Tick)) error {
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		record, err := parseRecordLine(line)
+		if err != nil {
+			slog.Warn("Skipping invalid line", "channel", channel, "error", err)
+			continue
+		}
+
+		publish(record.tick(channel, reader.source))
+	}
+
+	return scanner.Err()
+}
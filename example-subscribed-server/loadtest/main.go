@@ -0,0 +1,237 @@
+// Loadtest connects many simulated subscribers to the subscribed server and
+// reports how many frames and ticks they receive per second, run the server
+// with channels.bench.json and compare the rates with and without
+// -batch-latency. All the subscribers connect from one IP address, so the
+// server's per IP connection and message limits must be turned off or it
+// rejects most of them:
+//
+//	go run . -channels channels.bench.json -batch-latency 5ms -max-connections-per-ip 0 -ip-rate-limit 0
+//	go run ./loadtest -subscribers 2000 -duration 30s bench.>
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "handle-subscribed/protobuf"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/websocket"
+)
+
+// The error of a subscription the server rate limited
+var errRateLimited = errors.New("rate limited")
+
+// Counters are the totals of all subscribers, they are updated by the read
+// goroutine of each subscriber. Rejected counts the subscribers the server
+// refused with its rate limits
+type Counters struct {
+	frames   atomic.Int64
+	ticks    atomic.Int64
+	latency  atomic.Int64
+	errors   atomic.Int64
+	rejected atomic.Int64
+}
+
+func main() {
+	url := flag.String("url", "ws://localhost:8080/ws", "websocket url of the subscribed server")
+	subscribers := flag.Int("subscribers", 1000, "number of simulated subscribers")
+	duration := flag.Duration("duration", 10*time.Second, "how long the subscribers are measured after they are connected")
+	flag.Parse()
+
+	var channels = flag.Args()
+	if len(channels) == 0 {
+		channels = []string{">"}
+	}
+
+	var counters Counters
+	var connected sync.WaitGroup
+	var done = make(chan struct{})
+
+	for i := 0; i < *subscribers; i++ {
+		connected.Add(1)
+		go subscriber(*url, channels, strconv.Itoa(i), &counters, &connected, done)
+	}
+
+	connected.Wait()
+
+	// The rates mean nothing when part of the subscribers were refused
+	if rejected := counters.rejected.Load(); rejected > 0 {
+		slog.Error("Subscribers were rejected by the server's rate limits, start it with -max-connections-per-ip 0 -ip-rate-limit 0", "rejected", rejected, "subscribers", *subscribers)
+		os.Exit(1)
+	}
+
+	slog.Info("Subscribers connected, measuring", "connected", *subscribers-int(counters.errors.Load()), "failed", counters.errors.Load(), "duration", *duration)
+
+	// Start from zero once every subscriber is connected
+	counters.frames.Store(0)
+	counters.ticks.Store(0)
+	counters.latency.Store(0)
+
+	var start = time.Now()
+	var lastFrames, lastTicks int64
+	var ticker = time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for time.Since(start) < *duration {
+		<-ticker.C
+
+		frames, ticks := counters.frames.Load(), counters.ticks.Load()
+		fmt.Printf("%8d frames/s %10d ticks/s %8.2f ticks/frame\n", frames-lastFrames, ticks-lastTicks, ratio(ticks-lastTicks, frames-lastFrames))
+		lastFrames, lastTicks = frames, ticks
+	}
+	close(done)
+
+	var elapsed = time.Since(start).Seconds()
+	var frames, ticks = counters.frames.Load(), counters.ticks.Load()
+	fmt.Printf("total: %.0f frames/s, %.0f ticks/s, %.2f ticks/frame, mean latency %s\n",
+		float64(frames)/elapsed,
+		float64(ticks)/elapsed,
+		ratio(ticks, frames),
+		time.Duration(ratio(counters.latency.Load(), ticks)),
+	)
+}
+
+// Define a function to connect a subscriber, subscribe it to the channels and
+// count what it receives until done is closed, it is connected once the
+// server answered its subscription
+func subscriber(url string, channels []string, id string, counters *Counters, connected *sync.WaitGroup, done chan struct{}) {
+	conn, response, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		if response != nil && response.StatusCode == http.StatusTooManyRequests {
+			counters.rejected.Add(1)
+		} else {
+			counters.errors.Add(1)
+		}
+		connected.Done()
+		return
+	}
+	defer conn.Close()
+
+	request, err := proto.Marshal(&pb.ClientMessage{
+		Payload: &pb.ClientMessage_SubscriptionRequest{
+			SubscriptionRequest: &pb.SubscriptionRequest{
+				RequestId: id,
+				Action:    pb.SubscriptionAction_SUBSCRIBE,
+				Channels:  channels,
+			},
+		},
+	})
+	if err != nil {
+		slog.Error("Proto marshal error", "error", err)
+		os.Exit(1)
+	}
+
+	if err := conn.WriteMessage(websocket.BinaryMessage, request); err != nil {
+		counters.errors.Add(1)
+		connected.Done()
+		return
+	}
+
+	if err := waitForSubscription(conn); err != nil {
+		if websocket.IsCloseError(err, websocket.ClosePolicyViolation) || errors.Is(err, errRateLimited) {
+			counters.rejected.Add(1)
+		} else {
+			counters.errors.Add(1)
+		}
+		connected.Done()
+		return
+	}
+	connected.Done()
+
+	go func() {
+		<-done
+		conn.Close()
+	}()
+
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		if messageType != websocket.BinaryMessage {
+			continue
+		}
+
+		var message pb.WebSocketMessage
+		if err := proto.Unmarshal(data, &message); err != nil {
+			slog.Error("Proto unmarshal error", "error", err)
+			return
+		}
+
+		var ticks []*pb.Tick
+		switch payload := message.GetPaylod().(type) {
+		case *pb.WebSocketMessage_Tick:
+			ticks = []*pb.Tick{payload.Tick}
+		case *pb.WebSocketMessage_TickBatch:
+			ticks = payload.TickBatch.Ticks
+		default:
+			continue
+		}
+
+		var now = time.Now().UnixNano()
+		var latency int64
+		for _, tick := range ticks {
+			latency += now - tick.Timestamp
+		}
+
+		counters.frames.Add(1)
+		counters.ticks.Add(int64(len(ticks)))
+		counters.latency.Add(latency)
+	}
+}
+
+// Define a function to read the messages before the subscription response,
+// errRateLimited is returned when the server rate limited the request
+func waitForSubscription(conn *websocket.Conn) error {
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		if messageType != websocket.BinaryMessage {
+			continue
+		}
+
+		var message pb.WebSocketMessage
+		if err := proto.Unmarshal(data, &message); err != nil {
+			return err
+		}
+
+		if errorMessage := message.GetErrorMessage(); errorMessage != nil {
+			if errorMessage.ErrorCode == pb.ErrorCode_RATE_LIMITED {
+				return errRateLimited
+			}
+			return fmt.Errorf("subscription failed (%s): %s", errorMessage.ErrorCode, errorMessage.ErrorMessage)
+		}
+
+		if response := message.GetSubscriptionResponse(); response != nil {
+			if response.ErrorCode == pb.ErrorCode_RATE_LIMITED {
+				return errRateLimited
+			}
+			if !response.Success {
+				return fmt.Errorf("subscription failed (%s): %s", response.ErrorCode, response.Message)
+			}
+			return nil
+		}
+	}
+}
+
+// Divide two counters, 0 if there is nothing to divide by
+func ratio(a int64, b int64) float64 {
+	if b == 0 {
+		return 0
+	}
+
+	return float64(a) / float64(b)
+}
@@ -0,0 +1,38 @@
+package main
+
+import pb "handle-subscribed/protobuf"
+
+// Get the request id of a message received from a client, empty if it has none
+func clientMessageRequestID(message *pb.ClientMessage) string {
+	switch payload := message.GetPayload().(type) {
+	case *pb.ClientMessage_SubscriptionRequest:
+		return payload.SubscriptionRequest.RequestId
+	case *pb.ClientMessage_ListChannelsRequest:
+		return payload.ListChannelsRequest.RequestId
+	case *pb.ClientMessage_PublishRequest:
+		return payload.PublishRequest.RequestId
+	case *pb.ClientMessage_ResyncRequest:
+		return payload.ResyncRequest.RequestId
+	default:
+		return ""
+	}
+}
+
+// Get the id of the request a message sent to a client answers, empty if it
+// isn't an answer
+func webSocketMessageRequestID(message *pb.WebSocketMessage) string {
+	switch payload := message.GetPaylod().(type) {
+	case *pb.WebSocketMessage_ErrorMessage:
+		return payload.ErrorMessage.RequestId
+	case *pb.WebSocketMessage_SubscriptionResponse:
+		return payload.SubscriptionResponse.RequestId
+	case *pb.WebSocketMessage_ListChannelsResponse:
+		return payload.ListChannelsResponse.RequestId
+	case *pb.WebSocketMessage_PublishResponse:
+		return payload.PublishResponse.RequestId
+	case *pb.WebSocketMessage_ResyncResponse:
+		return payload.ResyncResponse.RequestId
+	default:
+		return ""
+	}
+}
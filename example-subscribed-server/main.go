@@ -1,112 +1,786 @@
 package main
 
 /*
- * It is a simple websocket server that will publish a random number every second
- * with a specific channel and will send it to all clients where subscribed to that channel
+ * It is a simple websocket server that will publish the values of a data source
+ * (a random number every second by default) with a specific channel and will send
+ * it to all clients where subscribed to that channel
  * build with protocol buffers
  */
 
 import (
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
 	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	pb "handle-subscribed/protobuf"
+	"shared/auth"
+	"shared/config"
+	"shared/logging"
+	"shared/origin"
+	"shared/ratelimit"
+	"shared/tlsconfig"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/gorilla/websocket"
 )
 
 type WebSocketServer struct {
-	clients    map[*websocket.Conn]bool
-	broadcast  chan map[string][]byte
-	register   chan *websocket.Conn
-	unregister chan *websocket.Conn
-	channels   map[*websocket.Conn]map[string]bool
+	clients    map[*Client]bool
+	broadcast  chan []*pb.Tick
+	register   chan *Client
+	unregister chan *Client
+	requests   chan *ClientRequest
+	channels   map[*Client]map[string]bool
+	filters    map[*Client]map[string]*Filter
+	throttles  map[*Client]*Throttle
+	flushes    chan *Client
+	topics     *TopicIndex
+	registry   *ChannelRegistry
+	history    map[string]*ChannelHistory
+
+	// The number of clients with a pattern matching each channel that has
+	// been counted, kept up to date by the subscriptions so the metrics and
+	// the channel list don't have to match every channel in the topic index
+	subscriberCounts map[string]int
+
+	// The number of ticks and how long they are kept in the history of each channel
+	historySize int
+	historyAge  time.Duration
+
+	// The durable log of every channel, nil when ticks are only kept in memory,
+	// and the number of ticks read from it for one replay or resync
+	logStore     *LogStore
+	logReadLimit int
+
+	// How the ticks broadcast to each client are batched into frames
+	batchOptions BatchOptions
+
+	// The sizes in bytes of the websocket read and write buffers, 0 uses the
+	// websocket library default, and the number of messages queued per client
+	readBufferSize  int
+	writeBufferSize int
+	sendBufferSize  int
+
+	// Checks the bearer token of a websocket upgrade, nil accepts every client
+	authenticator auth.Authenticator
+
+	// Checks the requests of the clients against the policies, nil allows all
+	authorizer *Authorizer
+
+	// Checks the Origin of a websocket upgrade from a browser
+	originChecker *origin.Checker
+
+	// Limits the connections and messages of each client and IP address
+	rateLimiter *ratelimit.Limiter
+
+	// The Prometheus metrics of the connections, messages and channels
+	metrics *Metrics
+
+	// The clock used for timestamps, retention and publisher pacing and the
+	// random source that seeds the publishers without a seed in their config,
+	// a test harness replaces them to get reproducible broadcasts
+	clock       Clock
+	random      *rand.Rand
+	randomMutex sync.Mutex
 }
 
-// This a method that will handle subscription requests coming from the client
-func (server *WebSocketServer) subscribe(client *websocket.Conn, channel string) {
+// ClientRequest is a message from a client that is handled by the run
+// goroutine, received is when it was read from the connection
+type ClientRequest struct {
+	client   *Client
+	message  *pb.ClientMessage
+	received time.Time
+}
+
+// The channels that are published when there is no channel config
+var defaultChannels = []*Channel{
+	{
+		Name:        "positive",
+		Description: "Random prices between 10 and 100",
+		Publisher:   randomSource,
+		Source:      &SourceConfig{Type: randomSource, Interval: Duration(time.Second), Min: 10, Max: 100, Scale: 2},
+	},
+	{
+		Name:        "negative",
+		Description: "Random prices between -100 and -10",
+		Publisher:   randomSource,
+		Source:      &SourceConfig{Type: randomSource, Interval: Duration(time.Second), Min: -100, Max: -10, Scale: 2},
+	},
+}
+
+// This a method that will handle subscription requests coming from the client,
+// the filter replaces the one of an earlier subscription to the channel and
+// may be nil to send every tick
+func (server *WebSocketServer) subscribe(client *Client, channel string, filter *Filter) {
 	if server.clients[client] {
 		if server.channels[client] == nil {
 			server.channels[client] = make(map[string]bool)
 		}
 
+		if !server.channels[client][channel] {
+			server.countSubscription(client, channel, 1)
+		}
+
 		server.channels[client][channel] = true
+		server.topics.insert(channel, client)
+		server.setFilter(client, channel, filter)
 	}
 }
 
-// This a method that will handle unsubscribe requests coming from the client
-func (server *WebSocketServer) unsubscribe(client *websocket.Conn, channel string) {
-	if server.clients[client] {
-		if server.channels[client] == nil {
-			server.channels[client] = make(map[string]bool)
+// This a method that will handle unsubscribe requests coming from the client,
+// the client is removed from the channel's subscribers right away
+func (server *WebSocketServer) unsubscribe(client *Client, channel string) {
+	if !server.channels[client][channel] {
+		return
+	}
+
+	server.countSubscription(client, channel, -1)
+	delete(server.channels[client], channel)
+	if len(server.channels[client]) == 0 {
+		delete(server.channels, client)
+	}
+
+	server.topics.remove(channel, client)
+	server.setFilter(client, channel, nil)
+}
+
+// Set the filter of the client's subscription to the channel, nil removes it
+func (server *WebSocketServer) setFilter(client *Client, channel string, filter *Filter) {
+	if filter == nil {
+		delete(server.filters[client], channel)
+		if len(server.filters[client]) == 0 {
+			delete(server.filters, client)
+		}
+		return
+	}
+
+	if server.filters[client] == nil {
+		server.filters[client] = make(map[string]*Filter)
+	}
+
+	server.filters[client][channel] = filter
+}
+
+// Check if the tick passes the filter of one of the client's subscriptions
+// that match its channel, a subscription without a filter passes every tick
+func (server *WebSocketServer) filterAllows(client *Client, tick *pb.Tick) bool {
+	filters, ok := server.filters[client]
+	if !ok {
+		return true
+	}
+
+	for pattern := range server.channels[client] {
+		if !patternMatches(pattern, tick.Channel) {
+			continue
+		}
+
+		if filter := filters[pattern]; filter == nil || filter.matches(tick) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// This a method that will handle a message coming from the client and send
+// the response back to it
+func (server *WebSocketServer) handleClientMessage(client *Client, message *pb.ClientMessage) {
+	// A request the policies deny is answered with an UNAUTHORIZED error
+	if errorMessage := server.authorize(client, message); errorMessage != nil {
+		client.sendWebSocketMessage(errorMessage)
+		return
+	}
+
+	switch message.GetPayload().(type) {
+	case *pb.ClientMessage_SubscriptionRequest:
+		request := message.GetSubscriptionRequest()
+
+		// A filter that can't be parsed is rejected before the request is applied
+		var filter *Filter
+		if request.Filter != "" {
+			var err error
+			if filter, err = parseFilter(request.Filter); err != nil {
+				errorMessage := newErrorMessage("invalid filter: "+err.Error(), pb.ErrorCode_INVALID_REQUEST)
+				errorMessage.GetErrorMessage().RequestId = request.RequestId
+				client.sendWebSocketMessage(errorMessage)
+				return
+			}
+		}
+
+		response := server.handleSubscriptionRequest(client, request, filter)
+		client.sendWebSocketMessage(&pb.WebSocketMessage{
+			Paylod: &pb.WebSocketMessage_SubscriptionResponse{
+				SubscriptionResponse: response,
+			},
+		})
+
+		// Send the requested history once the subscription is confirmed
+		if response.Success && request.Action == pb.SubscriptionAction_SUBSCRIBE && request.Replay != nil {
+			server.replay(client, request.Channels, request.Replay, filter)
+		}
+
+	case *pb.ClientMessage_ListChannelsRequest:
+		client.sendWebSocketMessage(&pb.WebSocketMessage{
+			Paylod: &pb.WebSocketMessage_ListChannelsResponse{
+				ListChannelsResponse: server.handleListChannelsRequest(message.GetListChannelsRequest()),
+			},
+		})
+
+	case *pb.ClientMessage_PublishRequest:
+		client.sendWebSocketMessage(&pb.WebSocketMessage{
+			Paylod: &pb.WebSocketMessage_PublishResponse{
+				PublishResponse: server.handlePublishRequest(client, message.GetPublishRequest()),
+			},
+		})
+
+	case *pb.ClientMessage_ResyncRequest:
+		client.sendWebSocketMessage(&pb.WebSocketMessage{
+			Paylod: &pb.WebSocketMessage_ResyncResponse{
+				ResyncResponse: server.handleResyncRequest(message.GetResyncRequest()),
+			},
+		})
+
+	default:
+		client.sendWebSocketMessage(newErrorMessage("unknown request", pb.ErrorCode_INVALID_REQUEST))
+	}
+}
+
+// This a method that will apply a subscription request and build the ack or nack
+// that is sent back to the client, a request with an unknown channel is rejected
+// as a whole so the client never ends up with a partial subscription
+func (server *WebSocketServer) handleSubscriptionRequest(client *Client, request *pb.SubscriptionRequest, filter *Filter) *pb.SubscriptionResponse {
+	var response = &pb.SubscriptionResponse{
+		RequestId: request.RequestId,
+		Action:    request.Action,
+	}
+
+	switch request.Action {
+	case pb.SubscriptionAction_SUBSCRIBE, pb.SubscriptionAction_UNSUBSCRIBE:
+		if len(request.Channels) == 0 {
+			response.Message = "no channel given"
+			response.ErrorCode = pb.ErrorCode_INVALID_REQUEST
+			return response
+		}
+
+		for _, channel := range request.Channels {
+			if err := validatePattern(channel); err != nil {
+				response.Message = err.Error()
+				response.ErrorCode = pb.ErrorCode_INVALID_REQUEST
+				return response
+			}
+
+			// A wildcard pattern may match channels that are created later
+			if !isWildcardPattern(channel) && server.registry.get(channel) == nil {
+				response.Message = fmt.Sprintf("unknown channel %q", channel)
+				response.ErrorCode = pb.ErrorCode_UNKNOWN_CHANNEL
+				return response
+			}
+		}
+
+		if request.Throttle != nil {
+			if err := validateThrottleOptions(request.Throttle); err != nil {
+				response.Message = err.Error()
+				response.ErrorCode = pb.ErrorCode_INVALID_REQUEST
+				return response
+			}
+		}
+
+		for _, channel := range request.Channels {
+			if request.Action == pb.SubscriptionAction_SUBSCRIBE {
+				server.subscribe(client, channel, filter)
+			} else {
+				server.unsubscribe(client, channel)
+			}
+		}
+
+		if request.Throttle != nil {
+			server.setThrottle(client, request.Throttle)
+		}
+
+	case pb.SubscriptionAction_LIST:
+
+	default:
+		response.Message = fmt.Sprintf("unsupported action %s", request.Action)
+		response.ErrorCode = pb.ErrorCode_INVALID_REQUEST
+		return response
+	}
+
+	response.Success = true
+	response.Channels = server.subscribedChannels(client)
+
+	return response
+}
+
+// This a method that will list the channels in the registry together with
+// their number of subscribers
+func (server *WebSocketServer) handleListChannelsRequest(request *pb.ListChannelsRequest) *pb.ListChannelsResponse {
+	var response = &pb.ListChannelsResponse{
+		RequestId: request.RequestId,
+	}
+
+	for _, channel := range server.registry.list() {
+		response.Channels = append(response.Channels, &pb.ChannelInfo{
+			Name:        channel.Name,
+			Description: channel.Description,
+			Publisher:   channel.Publisher,
+			Subscribers: int32(server.subscriberCount(channel.Name)),
+		})
+	}
+
+	return response
+}
+
+// This a method that will validate a publish request from the client and send
+// the payload to the subscribers of the channel
+func (server *WebSocketServer) handlePublishRequest(client *Client, request *pb.PublishRequest) *pb.PublishResponse {
+	var response = &pb.PublishResponse{
+		RequestId: request.RequestId,
+	}
+
+	if !channelNamePattern.MatchString(request.Channel) {
+		response.Message = fmt.Sprintf("invalid channel name %q", request.Channel)
+		response.ErrorCode = pb.ErrorCode_INVALID_REQUEST
+		return response
+	}
+
+	channel := server.registry.get(request.Channel)
+	if channel == nil {
+		response.Message = fmt.Sprintf("unknown channel %q", request.Channel)
+		response.ErrorCode = pb.ErrorCode_UNKNOWN_CHANNEL
+		return response
+	}
+
+	if !channel.ClientPublish {
+		response.Message = fmt.Sprintf("publishing to channel %q is not allowed", request.Channel)
+		response.ErrorCode = pb.ErrorCode_UNAUTHORIZED
+		return response
+	}
+
+	tick := newTick(channel.Name)
+	tick.Value = &pb.Tick_Text{Text: request.Payload}
+
+	var except *Client
+	if request.NoEcho {
+		except = client
+	}
+
+	if err := server.publish(tick, except); err != nil {
+		response.Message = "failed to marshal message"
+		response.ErrorCode = pb.ErrorCode_INTERNAL
+		return response
+	}
+
+	response.Success = true
+	response.Sequence = tick.Sequence
+
+	return response
+}
+
+// This a method that will look up the ticks a client missed in the channel history
+func (server *WebSocketServer) handleResyncRequest(request *pb.ResyncRequest) *pb.ResyncResponse {
+	var response = &pb.ResyncResponse{
+		RequestId: request.RequestId,
+		Channel:   request.Channel,
+	}
+
+	if server.registry.get(request.Channel) == nil {
+		response.Message = fmt.Sprintf("unknown channel %q", request.Channel)
+		response.ErrorCode = pb.ErrorCode_UNKNOWN_CHANNEL
+		return response
+	}
+
+	if request.ToSequence != 0 && request.FromSequence > request.ToSequence {
+		response.Message = "from_sequence is after to_sequence"
+		response.ErrorCode = pb.ErrorCode_INVALID_REQUEST
+		return response
+	}
+
+	response.Success = true
+	response.Complete = true
+
+	if history, ok := server.history[request.Channel]; ok {
+		response.Ticks, response.Complete = history.between(request.FromSequence, request.ToSequence)
+	}
+
+	// The durable log keeps ticks the memory history already dropped
+	if !response.Complete && server.logStore != nil {
+		channelLog, err := server.logStore.log(request.Channel)
+		if err == nil {
+			response.Ticks, response.Complete, err = channelLog.read(request.FromSequence, request.ToSequence, server.logReadLimit)
 		}
 
-		server.channels[client][channel] = false
+		if err != nil {
+			slog.Error("Error reading channel log", "channel", request.Channel, "error", err)
+			response.Success = false
+			response.Ticks = nil
+			response.Message = "failed to read channel log"
+			response.ErrorCode = pb.ErrorCode_INTERNAL
+		}
 	}
+
+	return response
 }
 
-// This a method that allows us to broadcast a message to all clients with specific channel
-// subscribed to it
-func (server *WebSocketServer) broadcastToSubscribers(channel string, message *[]byte) {
-	for client, channelMap := range server.channels {
-		// Check if the user is have subscribed to the channel
-		if channelMap[channel] {
-			err := client.WriteMessage(websocket.BinaryMessage, *message)
-			if err != nil {
-				fmt.Println("Error writing message:", err)
+// This a method that will send the ticks of the channel history selected by
+// the replay options to a client that just subscribed to the patterns, ticks
+// of several channels are sent in the order they were published and only the
+// ticks that pass the filter are sent if it isn't nil
+func (server *WebSocketServer) replay(client *Client, patterns []string, options *pb.ReplayOptions, filter *Filter) {
+	var ticks []*pb.Tick
+	for channel, history := range server.history {
+		for _, pattern := range patterns {
+			if patternMatches(pattern, channel) {
+				ticks = append(ticks, server.replayTicks(channel, history, options)...)
+				break
 			}
 		}
 	}
+
+	sort.SliceStable(ticks, func(i, j int) bool {
+		return ticks[i].Timestamp < ticks[j].Timestamp
+	})
+
+	// The ticks are sent in batches so a long replay doesn't fill the send
+	// queue of the client
+	var batch []*pb.Tick
+	for i, tick := range ticks {
+		if filter == nil || filter.matches(tick) {
+			// The history keeps the original tick, mark a copy as replayed
+			replayed := proto.Clone(tick).(*pb.Tick)
+			replayed.Replayed = true
+			batch = append(batch, replayed)
+		}
+
+		if len(batch) == 0 || (len(batch) < replayBatchSize && i < len(ticks)-1) {
+			continue
+		}
+
+		message, err := marshalTickBatch(batch)
+		if err != nil {
+			client.logger.Error("Error marshaling tick batch", "error", err)
+			return
+		}
+
+		// The rest of the replay would leave a gap if a batch is dropped
+		if !client.sendTickBatchMessage(message, len(batch)) {
+			client.logger.Warn("Replay truncated, client send buffer is full", "unsent", len(ticks)-i-1+len(batch))
+			return
+		}
+
+		batch = batch[:0]
+	}
 }
 
-func (server *WebSocketServer) sendTextMessage(conn *websocket.Conn, message *[]byte) {
-	err := conn.WriteMessage(websocket.TextMessage, *message)
+// Get the ticks of the channel selected by the replay options, replays from a
+// sequence number or a time are read from the durable log when it is enabled
+// since it keeps more than the memory history. A replay longer than the log
+// read limit stops after its first ticks, the client sees the rest as a gap
+// before the next live tick and resyncs it
+func (server *WebSocketServer) replayTicks(channel string, history *ChannelHistory, options *pb.ReplayOptions) []*pb.Tick {
+	if server.logStore == nil {
+		return history.replay(options)
+	}
+
+	channelLog, err := server.logStore.log(channel)
+	if err != nil {
+		slog.Error("Error opening channel log", "channel", channel, "error", err)
+		return history.replay(options)
+	}
+
+	var ticks []*pb.Tick
+	switch from := options.GetFrom().(type) {
+	case *pb.ReplayOptions_FromSequence:
+		ticks, _, err = channelLog.read(from.FromSequence, 0, server.logReadLimit)
+	case *pb.ReplayOptions_FromTimestamp:
+		ticks, _, err = channelLog.readSince(from.FromTimestamp, server.logReadLimit)
+	default:
+		return history.replay(options)
+	}
+
 	if err != nil {
-		fmt.Println("Error writing message:", err)
+		slog.Error("Error reading channel log", "channel", channel, "error", err)
+		return history.replay(options)
+	}
+
+	return ticks
+}
+
+// Get the history of the channel, a new history continues the sequence
+// numbers of the durable log so they stay unique across restarts
+func (server *WebSocketServer) channelHistory(channel string) *ChannelHistory {
+	if history, ok := server.history[channel]; ok {
+		return history
+	}
+
+	history := newChannelHistory(server.historySize, server.historyAge, server.clock)
+	if server.logStore != nil {
+		channelLog, err := server.logStore.log(channel)
+		if err != nil {
+			slog.Error("Error opening channel log", "channel", channel, "error", err)
+		} else {
+			history.lastSequence = channelLog.lastSequence()
+		}
+	}
+
+	server.history[channel] = history
+	server.subscriberCount(channel)
+
+	return history
+}
+
+// This a method that stamps the next sequence number of the channel on the
+// tick, keeps it in the channel history and the durable log and sends it to
+// the subscribers
+func (server *WebSocketServer) publish(tick *pb.Tick, except *Client) error {
+	tick.Timestamp = server.clock.Now().UnixNano()
+	server.channelHistory(tick.Channel).add(tick)
+	server.metrics.ticksPublished.WithLabelValues(tick.Channel).Inc()
+
+	if server.logStore != nil {
+		channelLog, err := server.logStore.log(tick.Channel)
+		if err == nil {
+			err = channelLog.append(tick)
+		}
+
+		if err != nil {
+			slog.Error("Error appending to channel log", "channel", tick.Channel, "error", err)
+		}
+	}
+
+	message, err := marshalTick(tick)
+	if err != nil {
+		return err
+	}
+
+	server.broadcastToSubscribers(tick, &message, except)
+
+	return nil
+}
+
+// Get the channels the client is subscribed to sorted by name
+func (server *WebSocketServer) subscribedChannels(client *Client) []string {
+	var channels []string
+	for channel := range server.channels[client] {
+		channels = append(channels, channel)
+	}
+
+	sort.Strings(channels)
+
+	return channels
+}
+
+// Get the number of clients with a pattern that matches the channel, it is
+// taken from the topic index the first time and kept up to date by the
+// subscriptions from then on
+func (server *WebSocketServer) subscriberCount(channel string) int {
+	count, ok := server.subscriberCounts[channel]
+	if !ok {
+		count = server.topics.count(channel)
+		server.setSubscriberCount(channel, count)
+	}
+
+	return count
+}
+
+// Set the subscriber count of the channel and its metric
+func (server *WebSocketServer) setSubscriberCount(channel string, count int) {
+	server.subscriberCounts[channel] = count
+	server.metrics.subscribers.WithLabelValues(channel).Set(float64(count))
+}
+
+// Change the subscriber counts of the channels the pattern matches when the
+// client subscribes to it (delta 1) or unsubscribes from it (delta -1), a
+// channel the client still gets through another pattern keeps its count
+func (server *WebSocketServer) countSubscription(client *Client, pattern string, delta int) {
+	for channel, count := range server.subscriberCounts {
+		if patternMatches(pattern, channel) && !server.matchesOtherPattern(client, pattern, channel) {
+			server.setSubscriberCount(channel, count+delta)
+		}
+	}
+}
+
+// Remove a client that disconnected from the subscriber counts
+func (server *WebSocketServer) uncountClient(client *Client) {
+	for channel, count := range server.subscriberCounts {
+		if server.matchesOtherPattern(client, "", channel) {
+			server.setSubscriberCount(channel, count-1)
+		}
+	}
+}
+
+// Check if one of the client's patterns other than except matches the channel
+func (server *WebSocketServer) matchesOtherPattern(client *Client, except string, channel string) bool {
+	for pattern := range server.channels[client] {
+		if pattern != except && patternMatches(pattern, channel) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// This a method that allows us to broadcast a tick to all clients with a
+// pattern matching its channel subscribed to it, except is skipped if not nil,
+// message is the marshaled tick sent to the clients without a throttle
+//
+// The shards of the topic index deliver the tick at the same time, which is
+// safe since the run goroutine waits for them and they only change the state
+// of their own clients
+func (server *WebSocketServer) broadcastToSubscribers(tick *pb.Tick, message *[]byte, except *Client) {
+	server.topics.fanOut(tick.Channel, func(client *Client) {
+		if client == except || !server.filterAllows(client, tick) {
+			return
+		}
+
+		if throttle, ok := server.throttles[client]; ok {
+			server.throttleTick(client, throttle, tick)
+			return
+		}
+
+		client.sendTickMessage(*message)
+	})
+}
+
+// Set the throttle of the client, options without an interval remove it, the
+// ticks still waiting in the old throttle are sent first
+func (server *WebSocketServer) setThrottle(client *Client, options *pb.ThrottleOptions) {
+	if throttle, ok := server.throttles[client]; ok {
+		server.flushThrottle(client, throttle)
+	}
+
+	if options.MinIntervalMs == 0 {
+		delete(server.throttles, client)
+		return
+	}
+
+	server.throttles[client] = newThrottle(options)
+}
+
+// Keep the tick in the client's throttle and send it right away if the
+// interval has passed, otherwise a flush is scheduled for when it does
+func (server *WebSocketServer) throttleTick(client *Client, throttle *Throttle, tick *pb.Tick) {
+	throttle.add(tick)
+	if throttle.scheduled {
+		return
+	}
+
+	wait := throttle.wait(server.clock.Now())
+	if wait == 0 {
+		server.flushThrottle(client, throttle)
+		return
+	}
+
+	throttle.scheduled = true
+	go func() {
+		<-server.clock.After(wait)
+		server.flushes <- client
+	}()
+}
+
+// Send the pending ticks of the client's throttle, batch mode sends them all
+// in one frame
+func (server *WebSocketServer) flushThrottle(client *Client, throttle *Throttle) {
+	throttle.scheduled = false
+
+	ticks := throttle.flush(server.clock.Now())
+	if len(ticks) == 0 {
+		return
+	}
+
+	if throttle.mode == pb.ConflationMode_BATCH {
+		message, err := marshalTickBatch(ticks)
+		if err != nil {
+			client.logger.Error("Error marshaling tick batch", "error", err)
+			return
+		}
+
+		client.sendTickBatchMessage(message, len(ticks))
+		return
+	}
+
+	for _, tick := range ticks {
+		message, err := marshalTick(tick)
+		if err != nil {
+			client.logger.Error("Error marshaling tick", "channel", tick.Channel, "error", err)
+			continue
+		}
+
+		client.sendTickMessage(message)
 	}
 }
 
 // This a method that will handle websocket requests coming from the client
 func (server *WebSocketServer) handleWebSocketConnection(w http.ResponseWriter, r *http.Request) {
+	// Check the bearer token before upgrading, a rejected client gets a 401
+	identity := auth.AuthenticateUpgrade(server.authenticator, w, r)
+	if identity == nil {
+		return
+	}
 
-	fmt.Println("New client connected:", r.RemoteAddr)
+	// Refuse the connection if its IP address has too many already
+	limits := server.rateLimiter.Connect(ratelimit.RemoteIP(r))
+	if limits == nil {
+		slog.Warn("Rejected connection, too many connections from its IP address", "remote_addr", r.RemoteAddr, "user", identity.Subject)
+		http.Error(w, "too many connections", http.StatusTooManyRequests)
+		return
+	}
+	defer limits.Close()
 
 	// Upgrade initial GET request to a websocket
 	upgrader := websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			return true
-		},
+		ReadBufferSize:  server.readBufferSize,
+		WriteBufferSize: server.writeBufferSize,
+		CheckOrigin:     server.originChecker.Check,
 	}
 
 	// Upgrade the connection to a websocket
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		fmt.Println("Error upgrading connection to websocket:", err)
+		slog.Warn("Error upgrading connection to websocket", "remote_addr", r.RemoteAddr, "user", identity.Subject, "error", err)
 		return
 	}
 
-	// Register our new client
-	server.register <- conn
+	// Register our new client, all writes to the connection go through its
+	// outgoing goroutine from now on
+	client := newClient(conn, identity, server.sendBufferSize, server.batchOptions, server.metrics)
+	client.logger.Info("New client connected", "auth_method", identity.Method)
+	go client.handleOutgoingMessage()
+	server.register <- client
 
 	var message = []byte(`---[ Welcome to subscribed-client ]---
 	Command list:
-	subs <channel>
-	unsubs <channel>
-	channel list: positive, negative`)
+	subs [-last | -seq <sequence> | -since <duration|time>] [-throttle <interval> [-batch]] <channel> [channel...] [-where <filter>]
+	filters use value, text, channel, sequence, timestamp, metadata.<key>, abs, min, max and contains: -where abs(value) < 20
+	unsubs <channel> [channel...]
+	channels may use wildcards, '*' matches one token and '>' the rest: prices.*.btc, prices.>
+	pub [-noecho] <channel> <message>
+	channels
+	subscriptions
+	exit
+	channel list: ` + strings.Join(server.registry.names(), ", "))
 
 	// Send initial message to the client
-	server.sendTextMessage(conn, &message)
+	client.sendTextMessage(message)
 
 	// Make sure we close the connection when the function returns
 	defer func() {
-		server.unregister <- conn
+		server.unregister <- client
 		conn.Close()
+		client.logger.Info("Client disconnected")
 	}()
 
+	// A message larger than the limit closes the connection
+	if server.rateLimiter.Options().MaxMessageSize > 0 {
+		conn.SetReadLimit(server.rateLimiter.Options().MaxMessageSize)
+	}
+
 	// Listen indefinitely for new messages coming
 	for {
 		_, msg, err := conn.ReadMessage()
@@ -114,140 +788,378 @@ func (server *WebSocketServer) handleWebSocketConnection(w http.ResponseWriter,
 			break
 		}
 
+		// Drop the messages over the rate limit and close the connection of a
+		// client that keeps going over it
+		allowed, abusive := limits.Allow()
+		if abusive {
+			client.logger.Warn("Closing connection for sending too many messages")
+			ratelimit.ClosePolicyViolation(conn, "too many messages")
+			break
+		}
+
+		if !allowed {
+			server.metrics.messagesDropped.WithLabelValues(dropRateLimited).Inc()
+			client.sendWebSocketMessage(newErrorMessage("rate limited", pb.ErrorCode_RATE_LIMITED))
+			continue
+		}
+
 		// Unmarshal the request
-		var request pb.SubscribeRequest
-		err = proto.Unmarshal(msg, &request)
+		var request = &pb.ClientMessage{}
+		err = proto.Unmarshal(msg, request)
 		if err != nil {
-			fmt.Println("Error unmarshaling request:", err)
+			client.logger.Warn("Error unmarshaling request", "error", err)
+			server.metrics.messagesDropped.WithLabelValues(dropInvalid).Inc()
+			client.sendWebSocketMessage(newErrorMessage("invalid request", pb.ErrorCode_INVALID_REQUEST))
 			continue
 		}
 
-		fmt.Println("Received message:", request.Channel)
+		client.logger.Debug("Received request", "request_id", clientMessageRequestID(request), "type", clientMessageType(request), "request", request.String())
+		server.metrics.messagesReceived.WithLabelValues(clientMessageType(request)).Inc()
 
-		// Subscribe or unsubscribe the client to the channel
-		// based on the request
-		if request.Channel == "unsubs positive" {
-			server.unsubscribe(conn, "positive")
+		// Handle the request in the run goroutine, it sends the response
+		// back once the request is applied
+		server.requests <- &ClientRequest{client: client, message: request, received: server.clock.Now()}
+	}
+}
 
-		} else if request.Channel == "unsubs negative" {
-			server.unsubscribe(conn, "negative")
+// Define a function to wrap an error message
+func newErrorMessage(message string, errorCode pb.ErrorCode) *pb.WebSocketMessage {
+	return &pb.WebSocketMessage{
+		Paylod: &pb.WebSocketMessage_ErrorMessage{
+			ErrorMessage: &pb.ErrorMessage{
+				ErrorMessage: message,
+				ErrorCode:    errorCode,
+			},
+		},
+	}
+}
 
-		} else if request.Channel == "subs positive" {
-			server.subscribe(conn, "positive")
+// This a goroutine that will run in the background and will publish the
+// ticks of the channel's source and send them to all clients where subscribed,
+// the channel is created if it isn't declared in the config and a random
+// source without a seed in the config uses the seed
+func (server *WebSocketServer) publisher(channel *Channel, seed int64) {
+	publisher, err := newPublisher(channel.Source, server.clock, seed)
+	if err != nil {
+		slog.Error("Error creating publisher", "channel", channel.Name, "error", err)
+		return
+	}
 
-		} else if request.Channel == "subs negative" {
-			server.subscribe(conn, "negative")
-		}
+	if _, err := server.registry.ensure(channel); err != nil {
+		slog.Error("Error creating channel", "channel", channel.Name, "error", err)
+		return
 	}
+
+	// Send the ticks to the clients, the sequence number is stamped when
+	// the tick is published
+	err = publisher.run(channel.Name, func(tick *pb.Tick) {
+		server.broadcast <- []*pb.Tick{tick}
+	})
+	if err != nil {
+		slog.Error("Error publishing", "channel", channel.Name, "error", err)
+		return
+	}
+
+	slog.Info("Publisher finished", "channel", channel.Name)
 }
 
-// This a goroutine that will run in the background and will
-// publish a random number every second and send it to all clients where subscribed
-func (server *WebSocketServer) publisher() {
-	for {
+// Get a seed for a publisher from the server's random source
+func (server *WebSocketServer) nextSeed() int64 {
+	server.randomMutex.Lock()
+	defer server.randomMutex.Unlock()
 
-		// Generate a random price
-		positive := 10.00 + rand.Float64()*(100.00-10.00)
-		negative := -10.00 + rand.Float64()*(-100.00+10.00)
+	return server.random.Int63()
+}
 
-		// Create a response
-		var positiveResponse = pb.WebSocketMessage{
-			Paylod: &pb.WebSocketMessage_SubscribeResponse{
-				SubscribeResponse: &pb.SubscribeResponse{
-					Message: fmt.Sprint(positive),
-				},
-			},
-		}
+// Create a new server with all of its maps and channels initialized, the
+// declared channels start with no subscribers
+func newWebSocketServer(registry *ChannelRegistry) *WebSocketServer {
+	var server = &WebSocketServer{
+		clients:    make(map[*Client]bool),
+		broadcast:  make(chan []*pb.Tick),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		requests:   make(chan *ClientRequest),
+		channels:   make(map[*Client]map[string]bool),
+		filters:    make(map[*Client]map[string]*Filter),
+		throttles:  make(map[*Client]*Throttle),
+		flushes:    make(chan *Client),
+		topics:     newTopicIndex(0),
+		registry:   registry,
+		history:    make(map[string]*ChannelHistory),
 
-		var negativeResponse = pb.WebSocketMessage{
-			Paylod: &pb.WebSocketMessage_SubscribeResponse{
-				SubscribeResponse: &pb.SubscribeResponse{
-					Message: fmt.Sprint(negative),
-				},
-			},
-		}
+		subscriberCounts: make(map[string]int),
 
-		// Check if the connection is still active before sending the price
-		// ok := server.clients[conn]
-		// if !ok {
-		// 	break
-		// }
+		historySize: defaultHistorySize,
+		historyAge:  defaultHistoryAge,
 
-		fmt.Println("Total client connected:", len(server.clients))
+		logReadLimit: defaultLogReadLimit,
 
-		// Marshal the response
-		postiveMessage, err := proto.Marshal(&positiveResponse)
-		if err != nil {
-			fmt.Println("Error marshaling response:", err)
-			break
-		}
+		batchOptions: BatchOptions{
+			maxTicks: defaultBatchMaxTicks,
+			maxBytes: defaultBatchMaxBytes,
+			clock:    RealClock{},
+		},
 
-		negativeMessage, err := proto.Marshal(&negativeResponse)
-		if err != nil {
-			fmt.Println("Error marshaling response:", err)
-			break
-		}
+		sendBufferSize: clientSendBufferSize,
 
-		// Send send message to specific channel
-		// where channel is key of a map and value is a message
-		var messageMap = make(map[string][]byte)
-		messageMap["positive"] = postiveMessage
-		messageMap["negative"] = negativeMessage
+		originChecker: &origin.Checker{},
+		rateLimiter:   ratelimit.New(ratelimit.DefaultOptions, RealClock{}.Now),
+		metrics:       newMetrics(),
 
-		// Send the message to the client
-		server.broadcast <- messageMap
+		clock:  RealClock{},
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
 
-		// Sleep for a second before sending the next update
-		time.Sleep(time.Second)
+	for _, channel := range registry.names() {
+		server.setSubscriberCount(channel, 0)
 	}
+
+	return server
 }
 
 // This a goroutine that will run in the background
 func (server *WebSocketServer) run() {
 	for {
 		select {
-		case conn := <-server.register:
+		case client := <-server.register:
 			// Register the new client
-			server.clients[conn] = true
+			server.clients[client] = true
+			server.metrics.connections.Inc()
 
-		case conn := <-server.unregister:
+		case client := <-server.unregister:
 			// Check if the connection is still active before unregistering it
-			if ok := server.clients[conn]; ok {
-				delete(server.clients, conn)
-				delete(server.channels, conn)
+			// and stop its outgoing goroutine
+			if ok := server.clients[client]; ok {
+				server.uncountClient(client)
+				server.topics.removeClient(client, server.channels[client])
+
+				delete(server.clients, client)
+				delete(server.channels, client)
+				delete(server.filters, client)
+				delete(server.throttles, client)
+				close(client.send)
+
+				server.metrics.connections.Dec()
 			}
 
+		case client := <-server.flushes:
+			// The throttle interval of the client has passed, the throttle is
+			// gone if the client disconnected or removed it in the meantime
+			if throttle, ok := server.throttles[client]; ok && throttle.scheduled {
+				server.flushThrottle(client, throttle)
+			}
+
+		case request := <-server.requests:
+			// Apply the request and send the response back to the client
+			server.handleClientMessage(request.client, request.message)
+
+			duration := server.clock.Now().Sub(request.received)
+			server.metrics.requestDuration.WithLabelValues(clientMessageType(request.message)).Observe(duration.Seconds())
+			request.client.logger.Debug("Handled request", "request_id", clientMessageRequestID(request.message), "type", clientMessageType(request.message), "duration", duration)
+
 		case message := <-server.broadcast:
-			// Send the message to all clients that are subscribed to the channel
-			for channel, byte := range message {
-				server.broadcastToSubscribers(channel, &byte)
+			slog.Debug("Broadcasting ticks", "ticks", len(message), "clients", len(server.clients))
+
+			// Send the ticks to all clients that are subscribed to their channel
+			for _, tick := range message {
+				if err := server.publish(tick, nil); err != nil {
+					slog.Error("Error marshaling tick", "channel", tick.Channel, "error", err)
+				}
 			}
 		}
 	}
 }
 
 func main() {
+	addr := flag.String("addr", ":8080", "address the server listens on")
+	wsPath := flag.String("ws-path", "/ws", "path of the websocket endpoint")
+	metricsPath := flag.String("metrics-path", "/metrics", "path of the Prometheus metrics endpoint, empty to disable it")
+	readBufferSize := flag.Int("read-buffer-size", 0, "size in bytes of the websocket read buffer, 0 uses the library default")
+	writeBufferSize := flag.Int("write-buffer-size", 0, "size in bytes of the websocket write buffer, 0 uses the library default")
+	sendBufferSize := flag.Int("send-buffer-size", clientSendBufferSize, "number of messages queued for a client before new ones are dropped")
+	channelConfig := flag.String("channels", "", "path to a JSON file declaring the channels")
+	historySize := flag.Int("history-size", defaultHistorySize, "number of ticks kept per channel for replay")
+	historyAge := flag.Duration("history-age", defaultHistoryAge, "how long ticks are kept for replay, 0 keeps them until they are pushed out by history-size")
+	logDir := flag.String("log-dir", "", "directory of the durable channel log, ticks are only kept in memory if empty")
+	logSegmentSize := flag.Int64("log-segment-size", defaultLogSegmentSize, "size in bytes at which a new log segment is started")
+	logMaxSize := flag.Int64("log-max-size", defaultLogMaxSize, "size in bytes of the log of a channel before old segments are removed, 0 for no limit")
+	logMaxAge := flag.Duration("log-max-age", 0, "age of the newest tick in a log segment before it is removed, 0 for no limit")
+	logReadLimit := flag.Int("log-read-limit", defaultLogReadLimit, "number of ticks read from the log of a channel for one replay or resync, the oldest are kept and clients ask for the rest, 0 for no limit")
+	seed := flag.Int64("seed", 0, "seed of the random sources without a seed in their config, 0 seeds from the current time")
+	batchLatency := flag.Duration("batch-latency", 0, "how long a broadcast tick may wait to be sent with others in one frame, 0 sends every tick in its own frame")
+	batchMaxTicks := flag.Int("batch-max-ticks", defaultBatchMaxTicks, "number of ticks at which a batch is sent")
+	batchMaxBytes := flag.Int("batch-max-bytes", defaultBatchMaxBytes, "size in bytes at which a batch is sent")
+	authTokens := flag.String("auth-tokens", "", "path to a file of bearer tokens and their subjects, one per line")
+	authJWTKey := flag.String("auth-jwt-key", "", "path to the HMAC key of the HS256 JWTs accepted as bearer tokens")
+	issueToken := flag.String("issue-token", "", "print a JWT for the subject signed with -auth-jwt-key and exit")
+	issueTokenTTL := flag.Duration("issue-token-ttl", 24*time.Hour, "how long a JWT printed by -issue-token is valid")
+	tlsCert := flag.String("tls-cert", "", "path to the PEM certificate of the server, serves wss:// with -tls-key")
+	tlsKey := flag.String("tls-key", "", "path to the PEM private key of the server certificate")
+	tlsClientCA := flag.String("tls-client-ca", "", "path to the PEM CA certificates that sign client certificates, clients must present one if set")
+	messageRate := flag.Float64("rate-limit", ratelimit.DefaultMessageRate, "messages per second a connection may send, 0 for no limit")
+	messageBurst := flag.Int("rate-burst", ratelimit.DefaultMessageBurst, "messages a connection may send at once above its rate")
+	ipMessageRate := flag.Float64("ip-rate-limit", ratelimit.DefaultIPMessageRate, "messages per second the connections of an IP address may send together, 0 for no limit")
+	ipMessageBurst := flag.Int("ip-rate-burst", ratelimit.DefaultIPMessageBurst, "messages the connections of an IP address may send at once above their rate")
+	maxConnectionsPerIP := flag.Int("max-connections-per-ip", ratelimit.DefaultMaxConnectionsPerIP, "connections an IP address may have open, 0 for no limit")
+	maxMessageSize := flag.Int64("max-message-size", ratelimit.DefaultMaxMessageSize, "size in bytes of the largest message a client may send, 0 for no limit")
+	maxViolations := flag.Int("max-violations", ratelimit.DefaultMaxViolations, "rate limited messages within -violation-window after which a connection is closed, 0 to never close it")
+	violationWindow := flag.Duration("violation-window", ratelimit.DefaultViolationWindow, "how long a rate limited message counts towards -max-violations, 0 counts it for the whole connection")
+	allowedOrigins := flag.String("allowed-origins", "", "comma separated origins browsers may connect from, like https://app.example.com or https://*.example.com")
+	allowAnyOrigin := flag.Bool("allow-any-origin", false, "accept websocket upgrades from any origin, only for development")
+	policyFile := flag.String("policies", "", "path to a JSON file of the policies that allow subjects to subscribe, publish and list channels, everything is allowed if empty")
+	logLevel := flag.String("log-level", "info", "lowest level of the logged lines, debug, info, warn or error")
+	logFormat := flag.String("log-format", logging.TextFormat, "format of the logs, text or json")
+	flag.String(config.ConfigFlag, "", "path to a JSON config file of flag values, relative paths in it are relative to its directory and environment variables and flags override it")
+	printConfig := flag.Bool(config.PrintConfigFlag, false, "print the effective settings and where they come from and exit")
+	flag.Parse()
+
+	// Fill the flags that weren't given from the environment and the config file
+	sources, err := config.Load(flag.CommandLine, "SUBSCRIBED_SERVER_", "channels", "log-dir", "auth-tokens", "auth-jwt-key", "tls-cert", "tls-key", "tls-client-ca", "policies")
+	if err != nil {
+		logging.Fatal("Error loading config", err)
+	}
+
+	if *printConfig {
+		config.Print(flag.CommandLine, sources)
+		return
+	}
+
+	// Write structured logs to stderr in the configured format
+	if err := logging.Setup(os.Stderr, *logLevel, *logFormat); err != nil {
+		logging.Fatal("Error configuring logs", err)
+	}
+
+	// Sign a token for a client instead of starting the server
+	if *issueToken != "" {
+		key, err := auth.LoadJWTKey(*authJWTKey)
+		if err != nil {
+			logging.Fatal("Error loading JWT key", err)
+		}
+
+		token, err := auth.IssueJWT(key, *issueToken, *issueTokenTTL, time.Now())
+		if err != nil {
+			logging.Fatal("Error issuing token", err)
+		}
+
+		fmt.Println(token)
+		return
+	}
+
+	// Declare the channels from the config
+	registry := newChannelRegistry()
+	if *channelConfig != "" {
+		if err := registry.loadConfig(*channelConfig); err != nil {
+			logging.Fatal("Error loading channel config", err)
+		}
+	}
+
 	// Create a new server
-	server := WebSocketServer{
-		clients:    make(map[*websocket.Conn]bool),
-		broadcast:  make(chan map[string][]byte),
-		register:   make(chan *websocket.Conn),
-		unregister: make(chan *websocket.Conn),
-		channels:   make(map[*websocket.Conn]map[string]bool),
+	server := newWebSocketServer(registry)
+	server.historySize = *historySize
+	server.historyAge = *historyAge
+	server.readBufferSize = *readBufferSize
+	server.writeBufferSize = *writeBufferSize
+	server.sendBufferSize = *sendBufferSize
+	if *seed != 0 {
+		server.random = rand.New(rand.NewSource(*seed))
+	}
+
+	authenticator, err := auth.NewAuthenticator(*authTokens, *authJWTKey, server.clock.Now)
+	if err != nil {
+		logging.Fatal("Error loading authentication config", err)
+	}
+	server.authenticator = authenticator
+
+	if server.originChecker, err = origin.NewChecker(*allowedOrigins, *allowAnyOrigin); err != nil {
+		logging.Fatal("Error loading allowed origins", err)
+	}
+
+	if *allowAnyOrigin {
+		slog.Warn("Accepting websocket upgrades from any origin, do not use -allow-any-origin in production")
+	}
+
+	server.rateLimiter = ratelimit.New(ratelimit.Options{
+		MessageRate:         *messageRate,
+		MessageBurst:        *messageBurst,
+		IPMessageRate:       *ipMessageRate,
+		IPMessageBurst:      *ipMessageBurst,
+		MaxConnectionsPerIP: *maxConnectionsPerIP,
+		MaxMessageSize:      *maxMessageSize,
+		MaxViolations:       *maxViolations,
+		ViolationWindow:     *violationWindow,
+	}, server.clock.Now)
+
+	if *policyFile != "" {
+		if server.authorizer, err = loadPolicies(*policyFile); err != nil {
+			logging.Fatal("Error loading policies", err)
+		}
+	}
+
+	server.batchOptions = BatchOptions{
+		maxTicks: *batchMaxTicks,
+		maxBytes: *batchMaxBytes,
+		latency:  *batchLatency,
+		clock:    server.clock,
+	}
+
+	// Open the durable log and continue the sequence numbers of its channels
+	if *logDir != "" {
+		logStore, err := openLogStore(*logDir, LogOptions{
+			segmentBytes: *logSegmentSize,
+			maxBytes:     *logMaxSize,
+			maxAge:       *logMaxAge,
+			clock:        server.clock,
+		})
+		if err != nil {
+			logging.Fatal("Error opening channel log", err)
+		}
+		defer logStore.close()
+
+		server.logStore = logStore
+		server.logReadLimit = *logReadLimit
+		for _, channel := range logStore.channels() {
+			server.channelHistory(channel)
+		}
 	}
 
 	// Setup route
-	http.HandleFunc("/ws", server.handleWebSocketConnection)
+	http.HandleFunc(*wsPath, server.handleWebSocketConnection)
+	if *metricsPath != "" {
+		http.Handle(*metricsPath, server.metrics.handler())
+	}
 
 	// Start the server
 	go server.run()
 
-	// Start the publisher
-	go server.publisher()
+	// Start the publishers of the channels with a source
+	var channels = registry.list()
+	if *channelConfig == "" {
+		channels = defaultChannels
+	}
+
+	for _, channel := range channels {
+		if channel.Source != nil {
+			// The seed is taken here so every channel gets the same seed on each run
+			go server.publisher(channel, server.nextSeed())
+		}
+	}
+
+	// Serve wss:// when a certificate is given, clients are identified by
+	// their certificate when a client CA is given as well
+	tlsConfig, err := tlsconfig.NewServer(*tlsCert, *tlsKey, *tlsClientCA)
+	if err != nil {
+		logging.Fatal("Error loading TLS config", err)
+	}
 
-	log.Println("Starting server...")
-	err := http.ListenAndServe(":8080", nil)
+	slog.Info("Starting server", "addr", *addr, "ws_path", *wsPath, "tls", tlsConfig != nil)
+	if tlsConfig != nil {
+		httpServer := &http.Server{Addr: *addr, TLSConfig: tlsConfig}
+		err = httpServer.ListenAndServeTLS("", "")
+	} else {
+		err = http.ListenAndServe(*addr, nil)
+	}
 	if err != nil {
-		log.Fatal("Error starting server:", err)
+		logging.Fatal("Error starting server", err)
 	}
 }
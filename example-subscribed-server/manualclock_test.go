@@ -0,0 +1,83 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ManualClock only moves when it is advanced, waiters are woken up in the
+// order of their deadline when the time reaches it
+type ManualClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	waiters []*ClockWaiter
+}
+
+// ClockWaiter is a pending After call of a ManualClock
+type ClockWaiter struct {
+	deadline time.Time
+	channel  chan time.Time
+}
+
+// Create a manual clock that starts at the time
+func newManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Get the current time of the clock
+func (clock *ManualClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	return clock.now
+}
+
+// Wait until the clock is advanced by the duration
+func (clock *ManualClock) After(duration time.Duration) <-chan time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	var channel = make(chan time.Time, 1)
+	if duration <= 0 {
+		channel <- clock.now
+		return channel
+	}
+
+	clock.waiters = append(clock.waiters, &ClockWaiter{deadline: clock.now.Add(duration), channel: channel})
+
+	return channel
+}
+
+// Move the clock forward and wake up the waiters whose deadline has passed
+func (clock *ManualClock) advance(duration time.Duration) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	clock.now = clock.now.Add(duration)
+
+	sort.SliceStable(clock.waiters, func(i, j int) bool {
+		return clock.waiters[i].deadline.Before(clock.waiters[j].deadline)
+	})
+
+	var pending []*ClockWaiter
+	for _, waiter := range clock.waiters {
+		if waiter.deadline.After(clock.now) {
+			pending = append(pending, waiter)
+			continue
+		}
+
+		waiter.channel <- clock.now
+	}
+
+	clock.waiters = pending
+}
+
+// Get the number of After calls that are still waiting, a test harness uses
+// it to know when the publishers are blocked before advancing the clock
+func (clock *ManualClock) waiting() int {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	return len(clock.waiters)
+}
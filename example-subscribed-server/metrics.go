@@ -0,0 +1,176 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	pb "handle-subscribed/protobuf"
+)
+
+// The reasons a message is dropped instead of handled or sent
+const (
+	dropSendBufferFull = "send_buffer_full"
+	dropRateLimited    = "rate_limited"
+	dropInvalid        = "invalid"
+)
+
+// Metrics are the Prometheus metrics of the server that are served on
+// /metrics, they can be updated from any goroutine
+type Metrics struct {
+	registry *prometheus.Registry
+
+	connections      prometheus.Gauge
+	messagesReceived *prometheus.CounterVec
+	messagesSent     *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	errors           *prometheus.CounterVec
+	subscribers      *prometheus.GaugeVec
+	ticksPublished   *prometheus.CounterVec
+	messagesDropped  *prometheus.CounterVec
+
+	// The counter of the ticks sent to the clients, it is kept apart since
+	// it is updated for every subscriber of every tick
+	ticksSent prometheus.Counter
+}
+
+// Create the metrics and register them with the Go runtime and process
+// metrics in a registry of their own
+func newMetrics() *Metrics {
+	var metrics = &Metrics{
+		registry: prometheus.NewRegistry(),
+		connections: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "subscribed_server",
+			Name:      "connections",
+			Help:      "Number of open websocket connections.",
+		}),
+		messagesReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "subscribed_server",
+			Name:      "messages_received_total",
+			Help:      "Messages received from the clients by type.",
+		}, []string{"type"}),
+		messagesSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "subscribed_server",
+			Name:      "messages_sent_total",
+			Help:      "Messages queued for the clients by type, ticks are counted one by one even when they are batched.",
+		}, []string{"type"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "subscribed_server",
+			Name:      "request_duration_seconds",
+			Help:      "Time from receiving a request to queueing its response by type.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, []string{"type"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "subscribed_server",
+			Name:      "errors_total",
+			Help:      "Errors and failed responses sent to the clients by error code.",
+		}, []string{"code"}),
+		subscribers: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "subscribed_server",
+			Name:      "channel_subscribers",
+			Help:      "Number of clients with a subscription matching the channel.",
+		}, []string{"channel"}),
+		ticksPublished: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "subscribed_server",
+			Name:      "ticks_published_total",
+			Help:      "Ticks published to the channel.",
+		}, []string{"channel"}),
+		messagesDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "subscribed_server",
+			Name:      "messages_dropped_total",
+			Help:      "Messages dropped instead of handled or sent by reason.",
+		}, []string{"reason"}),
+	}
+
+	metrics.ticksSent = metrics.messagesSent.WithLabelValues("tick")
+
+	metrics.registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		metrics.connections,
+		metrics.messagesReceived,
+		metrics.messagesSent,
+		metrics.requestDuration,
+		metrics.errors,
+		metrics.subscribers,
+		metrics.ticksPublished,
+		metrics.messagesDropped,
+	)
+
+	return metrics
+}
+
+// Get the HTTP handler that serves the metrics in the Prometheus text format
+func (metrics *Metrics) handler() http.Handler {
+	return promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
+}
+
+// Count a message sent to a client and its error code if it failed
+func (metrics *Metrics) messageSent(message *pb.WebSocketMessage) {
+	metrics.messagesSent.WithLabelValues(webSocketMessageType(message)).Inc()
+
+	if code, failed := webSocketMessageError(message); failed {
+		metrics.errors.WithLabelValues(errorCodeLabel(code)).Inc()
+	}
+}
+
+// Get the type of a message sent to a client for the metric labels
+func webSocketMessageType(message *pb.WebSocketMessage) string {
+	switch message.GetPaylod().(type) {
+	case *pb.WebSocketMessage_ErrorMessage:
+		return "error_message"
+	case *pb.WebSocketMessage_SubscriptionResponse:
+		return "subscription_response"
+	case *pb.WebSocketMessage_ListChannelsResponse:
+		return "list_channels_response"
+	case *pb.WebSocketMessage_PublishResponse:
+		return "publish_response"
+	case *pb.WebSocketMessage_Tick:
+		return "tick"
+	case *pb.WebSocketMessage_ResyncResponse:
+		return "resync_response"
+	case *pb.WebSocketMessage_TickBatch:
+		return "tick_batch"
+	default:
+		return "unknown"
+	}
+}
+
+// Get the error code of an error message or of a response that failed
+func webSocketMessageError(message *pb.WebSocketMessage) (pb.ErrorCode, bool) {
+	switch payload := message.GetPaylod().(type) {
+	case *pb.WebSocketMessage_ErrorMessage:
+		return payload.ErrorMessage.ErrorCode, true
+	case *pb.WebSocketMessage_SubscriptionResponse:
+		return payload.SubscriptionResponse.ErrorCode, !payload.SubscriptionResponse.Success
+	case *pb.WebSocketMessage_PublishResponse:
+		return payload.PublishResponse.ErrorCode, !payload.PublishResponse.Success
+	case *pb.WebSocketMessage_ResyncResponse:
+		return payload.ResyncResponse.ErrorCode, !payload.ResyncResponse.Success
+	default:
+		return pb.ErrorCode_UNKNOWN_ERROR, false
+	}
+}
+
+// Get the type of a message received from a client for the metric labels
+func clientMessageType(message *pb.ClientMessage) string {
+	switch message.GetPayload().(type) {
+	case *pb.ClientMessage_SubscriptionRequest:
+		return "subscription_request"
+	case *pb.ClientMessage_ListChannelsRequest:
+		return "list_channels_request"
+	case *pb.ClientMessage_PublishRequest:
+		return "publish_request"
+	case *pb.ClientMessage_ResyncRequest:
+		return "resync_request"
+	default:
+		return "unknown"
+	}
+}
+
+// Get the label of an error code like "unknown_channel"
+func errorCodeLabel(code pb.ErrorCode) string {
+	return strings.ToLower(code.String())
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	pb "handle-subscribed/protobuf"
+)
+
+// Get the value of every sample on /metrics by its name and labels as they
+// are written, like `subscribed_server_errors_total{code="unknown_channel"}`
+func scrapeMetrics(t *testing.T, testServer *httptest.Server) map[string]float64 {
+	t.Helper()
+
+	response, err := http.Get(testServer.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("scraping metrics: %v", err)
+	}
+	defer response.Body.Close()
+
+	var samples = make(map[string]float64)
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		separator := strings.LastIndexByte(line, ' ')
+		value, err := strconv.ParseFloat(line[separator+1:], 64)
+		if err != nil {
+			t.Fatalf("invalid sample %q: %v", line, err)
+		}
+
+		samples[line[:separator]] = value
+	}
+
+	return samples
+}
+
+// Scrape the metrics until the sample has the value, the run goroutine
+// updates some metrics after the client got its answer
+func waitForMetric(t *testing.T, testServer *httptest.Server, sample string, want float64) {
+	t.Helper()
+
+	var got float64
+	for deadline := time.Now().Add(testReadTimeout); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if got = scrapeMetrics(t, testServer)[sample]; got == want {
+			return
+		}
+	}
+
+	t.Fatalf("%s = %v, want %v", sample, got, want)
+}
+
+func TestMetricsScrape(t *testing.T) {
+	server := newTestServer(&Channel{Name: "prices.btc", ClientPublish: true}, &Channel{Name: "prices.eth"})
+	testServer := startTestServer(t, server)
+
+	first := dialTestClient(t, testServer)
+	second := dialTestClient(t, testServer)
+
+	first.subscribe("1", "", "prices.btc")
+	second.subscribe("1", "", "prices.>")
+
+	// A request for an unknown channel is counted as an error
+	second.send(&pb.ClientMessage{
+		Payload: &pb.ClientMessage_SubscriptionRequest{
+			SubscriptionRequest: &pb.SubscriptionRequest{
+				RequestId: "2",
+				Action:    pb.SubscriptionAction_SUBSCRIBE,
+				Channels:  []string{"unknown"},
+			},
+		},
+	})
+	if response := second.read().GetSubscriptionResponse(); response == nil || response.Success {
+		t.Fatalf("subscription to an unknown channel didn't fail: %v", response)
+	}
+
+	second.send(&pb.ClientMessage{
+		Payload: &pb.ClientMessage_PublishRequest{
+			PublishRequest: &pb.PublishRequest{RequestId: "3", Channel: "prices.btc", Payload: "up"},
+		},
+	})
+
+	// The tick is queued for the subscribers before the publish response
+	first.readTick()
+	second.readTick()
+	if response := second.read().GetPublishResponse(); response == nil || !response.Success {
+		t.Fatalf("publish failed: %v", response)
+	}
+
+	samples := scrapeMetrics(t, testServer)
+	for sample, want := range map[string]float64{
+		`subscribed_server_connections`:                                          2,
+		`subscribed_server_messages_received_total{type="subscription_request"}`: 3,
+		`subscribed_server_messages_received_total{type="publish_request"}`:      1,
+		`subscribed_server_messages_sent_total{type="subscription_response"}`:    3,
+		`subscribed_server_messages_sent_total{type="publish_response"}`:         1,
+		`subscribed_server_messages_sent_total{type="tick"}`:                     2,
+		`subscribed_server_errors_total{code="unknown_channel"}`:                 1,
+		`subscribed_server_ticks_published_total{channel="prices.btc"}`:          1,
+		`subscribed_server_channel_subscribers{channel="prices.btc"}`:            2,
+		`subscribed_server_channel_subscribers{channel="prices.eth"}`:            1,
+	} {
+		if got := samples[sample]; got != want {
+			t.Errorf("%s = %v, want %v", sample, got, want)
+		}
+	}
+
+	// The connection gauge goes down when a client disconnects
+	second.conn.Close()
+	waitForMetric(t, testServer, `subscribed_server_connections`, 1)
+	waitForMetric(t, testServer, `subscribed_server_channel_subscribers{channel="prices.eth"}`, 0)
+}
+
+func TestSubscriberCounts(t *testing.T) {
+	server := newTestServer(&Channel{Name: "prices.btc"}, &Channel{Name: "prices.eth"}, &Channel{Name: "news"})
+	testServer := startTestServer(t, server)
+
+	first := dialTestClient(t, testServer)
+	second := dialTestClient(t, testServer)
+
+	btc := `subscribed_server_channel_subscribers{channel="prices.btc"}`
+	eth := `subscribed_server_channel_subscribers{channel="prices.eth"}`
+	news := `subscribed_server_channel_subscribers{channel="news"}`
+
+	// Overlapping patterns of one client count it once
+	first.subscribe("1", "", "prices.btc", "prices.*")
+	first.subscribe("2", "", "prices.btc")
+	second.subscribe("1", "", ">")
+	waitForMetric(t, testServer, btc, 2)
+	waitForMetric(t, testServer, eth, 2)
+	waitForMetric(t, testServer, news, 1)
+
+	unsubscribe := func(client *TestClient, requestID string, channels ...string) {
+		client.send(&pb.ClientMessage{
+			Payload: &pb.ClientMessage_SubscriptionRequest{
+				SubscriptionRequest: &pb.SubscriptionRequest{
+					RequestId: requestID,
+					Action:    pb.SubscriptionAction_UNSUBSCRIBE,
+					Channels:  channels,
+				},
+			},
+		})
+		if response := client.read().GetSubscriptionResponse(); response == nil || !response.Success {
+			t.Fatalf("unsubscribe failed: %v", response)
+		}
+	}
+
+	// The first client still gets prices.btc through prices.btc
+	unsubscribe(first, "3", "prices.*")
+	waitForMetric(t, testServer, btc, 2)
+	waitForMetric(t, testServer, eth, 1)
+
+	// Unsubscribing from a channel the client isn't subscribed to changes nothing
+	unsubscribe(first, "4", "news")
+	waitForMetric(t, testServer, news, 1)
+
+	second.conn.Close()
+	waitForMetric(t, testServer, btc, 1)
+	waitForMetric(t, testServer, eth, 0)
+	waitForMetric(t, testServer, news, 0)
+
+	// A channel created after the subscriptions is counted from the topic
+	// index the first time it is needed
+	third := dialTestClient(t, testServer)
+	third.subscribe("1", "", "later.*")
+	if err := server.registry.declare(&Channel{Name: "later.channel"}); err != nil {
+		t.Fatal(err)
+	}
+
+	third.send(&pb.ClientMessage{
+		Payload: &pb.ClientMessage_ListChannelsRequest{
+			ListChannelsRequest: &pb.ListChannelsRequest{RequestId: "2"},
+		},
+	})
+	response := third.read().GetListChannelsResponse()
+	if response == nil {
+		t.Fatal("list channels request wasn't answered")
+	}
+
+	for _, channel := range response.Channels {
+		if channel.Name == "later.channel" && channel.Subscribers != 1 {
+			t.Errorf("later.channel has %d subscribers, want 1", channel.Subscribers)
+		}
+	}
+	waitForMetric(t, testServer, `subscribed_server_channel_subscribers{channel="later.channel"}`, 1)
+}
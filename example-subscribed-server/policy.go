@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+
+	pb "handle-subscribed/protobuf"
+	"shared/auth"
+	"shared/authz"
+)
+
+// The operations a policy can allow, subscribe also allows replaying and
+// resyncing the history of the channels
+const (
+	subscribeOperation = "subscribe"
+	publishOperation   = "publish"
+	listOperation      = "list"
+)
+
+// Policy allows the subjects to run the operations on the channels, a channel
+// may be a pattern like "prices.>" and channels are ignored for list
+type Policy struct {
+	authz.Policy
+	Channels []string `json:"channels"`
+}
+
+// Authorizer checks the operations of a client against the policies, anything
+// no policy allows is denied
+type Authorizer struct {
+	policies []*Policy
+}
+
+// Load and validate the policies from a JSON file
+func loadPolicies(path string) (*Authorizer, error) {
+	var policies []*Policy
+	if err := authz.ReadFile(path, &policies); err != nil {
+		return nil, err
+	}
+
+	for i, policy := range policies {
+		if err := policy.Validate(subscribeOperation, publishOperation, listOperation); err != nil {
+			return nil, fmt.Errorf("policy %d: %w", i+1, err)
+		}
+
+		for _, channel := range policy.Channels {
+			if err := validatePattern(channel); err != nil {
+				return nil, fmt.Errorf("policy %d: %w", i+1, err)
+			}
+		}
+	}
+
+	return &Authorizer{policies: policies}, nil
+}
+
+// Check if the identity may run the operation on the channel, a nil
+// authorizer allows everything
+func (authorizer *Authorizer) allowed(identity *auth.Identity, operation string, channel string) bool {
+	if authorizer == nil {
+		return true
+	}
+
+	for _, policy := range authorizer.policies {
+		if !policy.Allows(identity.Subject, operation) {
+			continue
+		}
+
+		if operation == listOperation {
+			return true
+		}
+
+		// A pattern is only allowed if the policy allows every channel it matches
+		for _, allowed := range policy.Channels {
+			if patternCovers(allowed, channel) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Check the client's message against the policies and build the
+// UNAUTHORIZED error sent back if it is denied, nil if it is allowed
+func (server *WebSocketServer) authorize(client *Client, message *pb.ClientMessage) *pb.WebSocketMessage {
+	var requestID, operation string
+	var channels []string
+
+	switch payload := message.GetPayload().(type) {
+	case *pb.ClientMessage_SubscriptionRequest:
+		// Unsubscribing and listing the subscriptions are always allowed
+		if payload.SubscriptionRequest.Action != pb.SubscriptionAction_SUBSCRIBE {
+			return nil
+		}
+		requestID, operation, channels = payload.SubscriptionRequest.RequestId, subscribeOperation, payload.SubscriptionRequest.Channels
+
+	case *pb.ClientMessage_ListChannelsRequest:
+		requestID, operation, channels = payload.ListChannelsRequest.RequestId, listOperation, []string{""}
+
+	case *pb.ClientMessage_PublishRequest:
+		requestID, operation, channels = payload.PublishRequest.RequestId, publishOperation, []string{payload.PublishRequest.Channel}
+
+	case *pb.ClientMessage_ResyncRequest:
+		requestID, operation, channels = payload.ResyncRequest.RequestId, subscribeOperation, []string{payload.ResyncRequest.Channel}
+
+	default:
+		return nil
+	}
+
+	for _, channel := range channels {
+		if server.authorizer.allowed(client.identity, operation, channel) {
+			continue
+		}
+
+		var reason = fmt.Sprintf("%s is not allowed to %s", client.identity.Subject, operation)
+		if channel != "" {
+			reason += fmt.Sprintf(" %q", channel)
+		}
+
+		errorMessage := newErrorMessage(reason, pb.ErrorCode_UNAUTHORIZED)
+		errorMessage.GetErrorMessage().RequestId = requestID
+		return errorMessage
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "handle-subscribed/protobuf"
+	"shared/auth"
+	"shared/authz"
+)
+
+func TestPatternCovers(t *testing.T) {
+	tests := []struct {
+		covering, pattern string
+		want              bool
+	}{
+		{covering: "prices.btc", pattern: "prices.btc", want: true},
+		{covering: "prices.btc", pattern: "prices.eth", want: false},
+		{covering: "prices.*", pattern: "prices.btc", want: true},
+		{covering: "prices.*", pattern: "prices.*", want: true},
+		{covering: "prices.*", pattern: "prices.>", want: false},
+		{covering: "prices.*", pattern: "prices.btc.usd", want: false},
+		{covering: "prices.*", pattern: "prices", want: false},
+		{covering: "prices.>", pattern: "prices.>", want: true},
+		{covering: "prices.>", pattern: "prices.*", want: true},
+		{covering: "prices.>", pattern: "prices.btc.usd", want: true},
+		{covering: "prices.>", pattern: "prices.*.usd", want: true},
+		{covering: "prices.>", pattern: "prices", want: false},
+		{covering: "prices.>", pattern: ">", want: false},
+		{covering: "prices.btc", pattern: "prices.*", want: false},
+		{covering: "*.btc", pattern: "prices.btc", want: true},
+		{covering: "*.btc", pattern: "*.btc", want: true},
+		{covering: "*.btc", pattern: ">", want: false},
+		{covering: ">", pattern: ">", want: true},
+		{covering: ">", pattern: "prices.*", want: true},
+	}
+
+	for _, test := range tests {
+		if got := patternCovers(test.covering, test.pattern); got != test.want {
+			t.Errorf("patternCovers(%q, %q) = %v, want %v", test.covering, test.pattern, got, test.want)
+		}
+	}
+}
+
+func TestAuthorizerAllowed(t *testing.T) {
+	authorizer := &Authorizer{policies: []*Policy{
+		{Policy: authz.Policy{Subjects: []string{"*"}, Operations: []string{listOperation, subscribeOperation}}, Channels: []string{"positive", "negative"}},
+		{Policy: authz.Policy{Subjects: []string{"alice"}, Operations: []string{subscribeOperation, publishOperation}}, Channels: []string{"prices.*", "chat"}},
+		{Policy: authz.Policy{Subjects: []string{"admin"}, Operations: []string{authz.Any}}, Channels: []string{">"}},
+	}}
+
+	tests := []struct {
+		subject, operation, channel string
+		want                        bool
+	}{
+		{subject: "bob", operation: listOperation, want: true},
+		{subject: "bob", operation: subscribeOperation, channel: "positive", want: true},
+		{subject: "bob", operation: subscribeOperation, channel: "prices.btc", want: false},
+		{subject: "bob", operation: publishOperation, channel: "positive", want: false},
+		{subject: "alice", operation: subscribeOperation, channel: "prices.btc", want: true},
+		{subject: "alice", operation: subscribeOperation, channel: "prices.*", want: true},
+		{subject: "alice", operation: subscribeOperation, channel: "prices.>", want: false},
+		{subject: "alice", operation: subscribeOperation, channel: "prices.btc.usd", want: false},
+		{subject: "alice", operation: publishOperation, channel: "chat", want: true},
+		{subject: "alice", operation: publishOperation, channel: "positive", want: false},
+		{subject: "alice", operation: subscribeOperation, channel: "negative", want: true},
+		{subject: "admin", operation: publishOperation, channel: "prices.btc", want: true},
+		{subject: "admin", operation: subscribeOperation, channel: ">", want: true},
+	}
+
+	for _, test := range tests {
+		identity := &auth.Identity{Subject: test.subject, Method: "token"}
+		if got := authorizer.allowed(identity, test.operation, test.channel); got != test.want {
+			t.Errorf("%s %s %q = %v, want %v", test.subject, test.operation, test.channel, got, test.want)
+		}
+	}
+
+	// Without policies everything is allowed
+	var none *Authorizer
+	if !none.allowed(&auth.Identity{Subject: "anonymous", Method: "none"}, publishOperation, "chat") {
+		t.Error("a nil authorizer denied a publish")
+	}
+}
+
+func TestLoadPolicies(t *testing.T) {
+	if _, err := loadPolicies("policies.example.json"); err != nil {
+		t.Fatalf("loading the example policies: %v", err)
+	}
+
+	tests := map[string]string{
+		"no subjects":       `{"policies": [{"operations": ["list"]}]}`,
+		"unknown operation": `{"policies": [{"subjects": ["*"], "operations": ["delete"]}]}`,
+		"invalid pattern":   `{"policies": [{"subjects": ["*"], "operations": ["subscribe"], "channels": ["prices.>.btc"]}]}`,
+		"invalid JSON":      `{"policies": [`,
+	}
+
+	for name, config := range tests {
+		path := filepath.Join(t.TempDir(), "policies.json")
+		if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := loadPolicies(path); err == nil {
+			t.Errorf("%s: loaded %s", name, config)
+		}
+	}
+}
+
+func TestUnauthorizedRequests(t *testing.T) {
+	server := newTestServer(&Channel{Name: "prices.btc", ClientPublish: true})
+	server.authorizer = &Authorizer{policies: []*Policy{
+		{Policy: authz.Policy{Subjects: []string{"anonymous"}, Operations: []string{subscribeOperation}}, Channels: []string{"prices.*"}},
+	}}
+
+	client := dialTestClient(t, startTestServer(t, server))
+
+	// The grant on prices.* doesn't cover every channel prices.> matches
+	client.send(&pb.ClientMessage{
+		Payload: &pb.ClientMessage_SubscriptionRequest{
+			SubscriptionRequest: &pb.SubscriptionRequest{
+				RequestId: "1",
+				Action:    pb.SubscriptionAction_SUBSCRIBE,
+				Channels:  []string{"prices.>"},
+			},
+		},
+	})
+	checkUnauthorized(t, client.read(), "1")
+
+	client.send(&pb.ClientMessage{
+		Payload: &pb.ClientMessage_PublishRequest{
+			PublishRequest: &pb.PublishRequest{RequestId: "2", Channel: "prices.btc", Payload: "1"},
+		},
+	})
+	checkUnauthorized(t, client.read(), "2")
+
+	// The channels the grant covers are allowed
+	client.subscribe("3", "", "prices.btc")
+}
+
+// Check that the message is the UNAUTHORIZED error of the request
+func checkUnauthorized(t *testing.T, message *pb.WebSocketMessage, requestID string) {
+	t.Helper()
+
+	errorMessage := message.GetErrorMessage()
+	if errorMessage == nil || errorMessage.ErrorCode != pb.ErrorCode_UNAUTHORIZED || errorMessage.RequestId != requestID {
+		t.Fatalf("got %v, want the UNAUTHORIZED error of request %s", message, requestID)
+	}
+}
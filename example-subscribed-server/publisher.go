@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	pb "handle-subscribed/protobuf"
+)
+
+// The types of data source a channel can be published from
+const (
+	randomSource = "random"
+	fileSource   = "file"
+	stdinSource  = "stdin"
+	pipeSource   = "pipe"
+)
+
+// The largest number of digits after the point of a parsed decimal
+const maxDecimalScale = 18
+
+// Publisher is a data source that produces the ticks of a channel, run blocks
+// until the source is exhausted or fails
+type Publisher interface {
+	run(channel string, publish func(tick *pb.Tick)) error
+}
+
+// SourceConfig is the "source" of a channel in the channel config, the fields
+// that are used depend on the type of the source
+type SourceConfig struct {
+	Type string `json:"type"`
+
+	// How often a tick is published, for a file without it the ticks are
+	// spaced by their recorded timestamps or a second apart without them
+	Interval Duration `json:"interval"`
+
+	// The random walk starts at start and moves at most step per tick while
+	// staying between min and max, without a step every tick is a uniform
+	// random value between them. The values are rounded to scale digits
+	Seed  int64   `json:"seed"`
+	Start float64 `json:"start"`
+	Step  float64 `json:"step"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Scale int32   `json:"scale"`
+
+	// The file or named pipe to read, a file is csv or jsonl and the format
+	// is taken from its extension if it isn't set
+	Path   string `json:"path"`
+	Format string `json:"format"`
+	Loop   bool   `json:"loop"`
+}
+
+// Duration is a time.Duration that is written as a string like "500ms" in JSON
+type Duration time.Duration
+
+// Parse a duration string like "500ms" from JSON
+func (duration *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+
+	*duration = Duration(parsed)
+
+	return nil
+}
+
+// Create the publisher for the source config, it waits between ticks with the
+// clock and a random source without a seed in the config uses the seed
+func newPublisher(config *SourceConfig, clock Clock, seed int64) (Publisher, error) {
+	switch config.Type {
+	case randomSource:
+		return newRandomWalk(config, clock, seed)
+	case fileSource:
+		return newFileReplay(config, clock)
+	case stdinSource, pipeSource:
+		return newLineReader(config)
+	default:
+		return nil, fmt.Errorf("unknown source type %q", config.Type)
+	}
+}
+
+// Parse a decimal number like "12.30" into a fixed-point decimal that keeps
+// all of its digits, a number with an exponent gets the smallest scale that
+// represents it exactly
+func parseDecimal(text string) (*pb.Decimal, error) {
+	var value, ok = new(big.Rat).SetString(text)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %q", text)
+	}
+
+	var scale int32
+	if point := strings.IndexByte(text, '.'); point >= 0 && !strings.ContainsAny(text, "eE") {
+		scale = int32(len(text) - point - 1)
+	}
+
+	var units = new(big.Int)
+	for ; scale <= maxDecimalScale; scale++ {
+		var remainder = new(big.Int)
+		units.Mul(value.Num(), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil))
+		units.QuoRem(units, value.Denom(), remainder)
+		if remainder.Sign() == 0 {
+			break
+		}
+	}
+
+	if scale > maxDecimalScale || !units.IsInt64() {
+		return nil, fmt.Errorf("number %q is out of range", text)
+	}
+
+	return &pb.Decimal{Units: units.Int64(), Scale: scale}, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	pb "handle-subscribed/protobuf"
+)
+
+// RandomWalk publishes a value that moves a random amount every interval, or
+// a new uniform random value every interval when it has no step
+type RandomWalk struct {
+	clock    Clock
+	random   *rand.Rand
+	interval time.Duration
+	value    float64
+	step     float64
+	min      float64
+	max      float64
+	scale    int32
+}
+
+// Create a random walk from the source config, the seed is used if the config
+// doesn't have one
+func newRandomWalk(config *SourceConfig, clock Clock, seed int64) (*RandomWalk, error) {
+	if config.Max < config.Min {
+		return nil, errors.New("random source max is less than min")
+	}
+
+	if config.Seed != 0 {
+		seed = config.Seed
+	}
+
+	var walk = &RandomWalk{
+		clock:    clock,
+		random:   rand.New(rand.NewSource(seed)),
+		interval: time.Duration(config.Interval),
+		value:    config.Start,
+		step:     config.Step,
+		min:      config.Min,
+		max:      config.Max,
+		scale:    config.Scale,
+	}
+
+	if walk.interval <= 0 {
+		walk.interval = time.Second
+	}
+
+	if walk.value < walk.min || walk.value > walk.max {
+		walk.value = walk.min + (walk.max-walk.min)/2
+	}
+
+	return walk, nil
+}
+
+// Publish the next value of the walk every interval, it never returns
+func (walk *RandomWalk) run(channel string, publish func(tick *pb.Tick)) error {
+	for {
+		walk.next()
+
+		tick := newTick(channel)
+		tick.Value = &pb.Tick_DecimalValue{DecimalValue: newDecimal(walk.value, walk.scale)}
+		tick.Metadata = map[string]string{"source": randomSource}
+		publish(tick)
+
+		// Wait before sending the next update
+		<-walk.clock.After(walk.interval)
+	}
+}
+
+// Move the value at most step in either direction while staying between min
+// and max, without a step it is a uniform random value between them
+func (walk *RandomWalk) next() {
+	if walk.step <= 0 {
+		walk.value = walk.min + walk.random.Float64()*(walk.max-walk.min)
+		return
+	}
+
+	walk.value += (walk.random.Float64()*2 - 1) * walk.step
+	if walk.value < walk.min {
+		walk.value = walk.min
+	} else if walk.value > walk.max {
+		walk.value = walk.max
+	}
+}
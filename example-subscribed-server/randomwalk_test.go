@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRandomWalkStep(t *testing.T) {
+	walk, err := newRandomWalk(&SourceConfig{Start: 50, Step: 2, Min: 10, Max: 100, Seed: 1}, newManualClock(time.Unix(0, 0)), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10000; i++ {
+		previous := walk.value
+		walk.next()
+		if walk.value < 10 || walk.value > 100 || walk.value-previous > 2 || previous-walk.value > 2 {
+			t.Fatalf("tick %d moved from %v to %v, want at most 2 between 10 and 100", i, previous, walk.value)
+		}
+	}
+}
+
+func TestRandomWalkUniform(t *testing.T) {
+	walk, err := newRandomWalk(&SourceConfig{Min: 10, Max: 100, Seed: 1}, newManualClock(time.Unix(0, 0)), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a step the values are spread between min and max instead of
+	// being clamped to them
+	const ticks = 10000
+	var bounds int
+	var buckets [9]int
+	for i := 0; i < ticks; i++ {
+		walk.next()
+		if walk.value < 10 || walk.value > 100 {
+			t.Fatalf("tick %d is %v, want it between 10 and 100", i, walk.value)
+		}
+		if walk.value == 10 || walk.value == 100 {
+			bounds++
+		}
+		buckets[int(walk.value-10)/10%len(buckets)]++
+	}
+
+	if bounds > ticks/100 {
+		t.Errorf("%d of %d ticks are on min or max", bounds, ticks)
+	}
+	for i, count := range buckets {
+		if count < ticks/len(buckets)/2 {
+			t.Errorf("%d of %d ticks are between %d and %d", count, ticks, 10+i*10, 20+i*10)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// A channel name is one or more dot separated words of letters, digits, '-' and '_'
+var channelNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`)
+
+// Channel holds the metadata of a channel clients can subscribe to, clients
+// may only publish to channels that allow it and the server publishes the
+// ticks of the source if it has one
+type Channel struct {
+	Name          string        `json:"name"`
+	Description   string        `json:"description"`
+	Publisher     string        `json:"publisher"`
+	ClientPublish bool          `json:"client_publish"`
+	Source        *SourceConfig `json:"source"`
+}
+
+// ChannelConfig is the format of the file channels are declared in
+type ChannelConfig struct {
+	Channels []*Channel `json:"channels"`
+}
+
+// ChannelRegistry holds the channels that exist on the server, channels are
+// declared from config at startup or created on demand by a publisher
+type ChannelRegistry struct {
+	mutex    sync.RWMutex
+	channels map[string]*Channel
+}
+
+// Create a new empty channel registry
+func newChannelRegistry() *ChannelRegistry {
+	return &ChannelRegistry{
+		channels: make(map[string]*Channel),
+	}
+}
+
+// Load the channels declared in a JSON config file into the registry
+func (registry *ChannelRegistry) loadConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var config ChannelConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	for _, channel := range config.Channels {
+		if channel.Source != nil {
+			// Check the source now rather than when it starts publishing
+			if _, err := newPublisher(channel.Source, RealClock{}, 1); err != nil {
+				return fmt.Errorf("parsing %s: channel %q: %w", path, channel.Name, err)
+			}
+
+			if channel.Publisher == "" {
+				channel.Publisher = channel.Source.Type
+			}
+		}
+
+		if err := registry.declare(channel); err != nil {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+// Add a channel to the registry, declaring a channel twice is an error
+func (registry *ChannelRegistry) declare(channel *Channel) error {
+	if !channelNamePattern.MatchString(channel.Name) {
+		return fmt.Errorf("invalid channel name %q", channel.Name)
+	}
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if _, ok := registry.channels[channel.Name]; ok {
+		return fmt.Errorf("channel %q is declared twice", channel.Name)
+	}
+
+	registry.channels[channel.Name] = channel
+
+	return nil
+}
+
+// Get the channel with the name or create it for the publisher if it doesn't exist yet
+func (registry *ChannelRegistry) ensure(channel *Channel) (*Channel, error) {
+	if !channelNamePattern.MatchString(channel.Name) {
+		return nil, fmt.Errorf("invalid channel name %q", channel.Name)
+	}
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if existing, ok := registry.channels[channel.Name]; ok {
+		return existing, nil
+	}
+
+	registry.channels[channel.Name] = channel
+
+	return channel, nil
+}
+
+// Get the channel with the name, nil is returned if it doesn't exist
+func (registry *ChannelRegistry) get(name string) *Channel {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	return registry.channels[name]
+}
+
+// List all channels sorted by name
+func (registry *ChannelRegistry) list() []*Channel {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	var channels = make([]*Channel, 0, len(registry.channels))
+	for _, channel := range registry.channels {
+		channels = append(channels, channel)
+	}
+
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].Name < channels[j].Name
+	})
+
+	return channels
+}
+
+// List the names of all channels sorted by name
+func (registry *ChannelRegistry) names() []string {
+	var names []string
+	for _, channel := range registry.list() {
+		names = append(names, channel.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	pb "handle-subscribed/protobuf"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/websocket"
+)
+
+// How long a test client waits for a message before failing
+const testReadTimeout = 5 * time.Second
+
+// TestClient is a websocket connection of a test to a test server
+type TestClient struct {
+	t    testing.TB
+	conn *websocket.Conn
+}
+
+// Drop the logs of the servers started by the tests
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// Create a server for the channels that publishes nothing on its own
+func newTestServer(channels ...*Channel) *WebSocketServer {
+	registry := newChannelRegistry()
+	for _, channel := range channels {
+		if err := registry.declare(channel); err != nil {
+			panic(err)
+		}
+	}
+
+	return newWebSocketServer(registry)
+}
+
+// Start the run goroutine of the server and serve its websocket endpoint on
+// /ws and its metrics on /metrics of a test HTTP server
+func startTestServer(t testing.TB, server *WebSocketServer) *httptest.Server {
+	t.Helper()
+
+	go server.run()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", server.handleWebSocketConnection)
+	mux.Handle("/metrics", server.metrics.handler())
+
+	testServer := httptest.NewServer(mux)
+	t.Cleanup(testServer.Close)
+
+	return testServer
+}
+
+// Connect to the websocket endpoint of the test server and read the welcome
+// message
+func dialTestClient(t testing.TB, testServer *httptest.Server) *TestClient {
+	t.Helper()
+
+	url := "ws" + strings.TrimPrefix(testServer.URL, "http") + "/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial %s: %v", url, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	var client = &TestClient{t: t, conn: conn}
+
+	conn.SetReadDeadline(time.Now().Add(testReadTimeout))
+	messageType, _, err := conn.ReadMessage()
+	if err != nil || messageType != websocket.TextMessage {
+		t.Fatalf("reading welcome message: type %d, %v", messageType, err)
+	}
+
+	return client
+}
+
+// Send a message to the server
+func (client *TestClient) send(message *pb.ClientMessage) {
+	client.t.Helper()
+
+	data, err := proto.Marshal(message)
+	if err != nil {
+		client.t.Fatal(err)
+	}
+
+	if err := client.conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+		client.t.Fatalf("sending message: %v", err)
+	}
+}
+
+// Read the next message from the server
+func (client *TestClient) read() *pb.WebSocketMessage {
+	client.t.Helper()
+
+	client.conn.SetReadDeadline(time.Now().Add(testReadTimeout))
+	_, data, err := client.conn.ReadMessage()
+	if err != nil {
+		client.t.Fatalf("reading message: %v", err)
+	}
+
+	var message = &pb.WebSocketMessage{}
+	if err := proto.Unmarshal(data, message); err != nil {
+		client.t.Fatalf("unmarshaling message: %v", err)
+	}
+
+	return message
+}
+
+// Read the next message, it must be a tick
+func (client *TestClient) readTick() *pb.Tick {
+	client.t.Helper()
+
+	message := client.read()
+	tick := message.GetTick()
+	if tick == nil {
+		client.t.Fatalf("got %s, want a tick", webSocketMessageType(message))
+	}
+
+	return tick
+}
+
+// Send a subscription request for the channels and return the response
+func (client *TestClient) subscribe(requestID string, filter string, channels ...string) *pb.SubscriptionResponse {
+	client.t.Helper()
+
+	client.send(&pb.ClientMessage{
+		Payload: &pb.ClientMessage_SubscriptionRequest{
+			SubscriptionRequest: &pb.SubscriptionRequest{
+				RequestId: requestID,
+				Action:    pb.SubscriptionAction_SUBSCRIBE,
+				Channels:  channels,
+				Filter:    filter,
+			},
+		},
+	})
+
+	message := client.read()
+	response := message.GetSubscriptionResponse()
+	if response == nil {
+		client.t.Fatalf("got %s, want a subscription response", webSocketMessageType(message))
+	}
+
+	if !response.Success {
+		client.t.Fatalf("subscription %s failed (%s): %s", requestID, response.ErrorCode, response.Message)
+	}
+
+	return response
+}
+
+func TestPublishNoEcho(t *testing.T) {
+	server := newTestServer(&Channel{Name: "chat", ClientPublish: true})
+	testServer := startTestServer(t, server)
+
+	publisher := dialTestClient(t, testServer)
+	subscriber := dialTestClient(t, testServer)
+	publisher.subscribe("1", "", "chat")
+	subscriber.subscribe("1", "", "chat")
+
+	for i, noEcho := range []bool{false, true, false} {
+		publisher.send(&pb.ClientMessage{
+			Payload: &pb.ClientMessage_PublishRequest{
+				PublishRequest: &pb.PublishRequest{RequestId: strconv.Itoa(i + 2), Channel: "chat", Payload: "hi", NoEcho: noEcho},
+			},
+		})
+
+		want := uint64(i + 1)
+		if tick := subscriber.readTick(); tick.Sequence != want {
+			t.Fatalf("subscriber got sequence %d, want %d", tick.Sequence, want)
+		}
+
+		// Without echo the publisher only gets the response, it carries the
+		// sequence number of the tick it was left out of
+		if !noEcho {
+			if tick := publisher.readTick(); tick.Sequence != want {
+				t.Fatalf("publisher got sequence %d, want %d", tick.Sequence, want)
+			}
+		}
+
+		response := publisher.read().GetPublishResponse()
+		if response == nil || !response.Success || response.Sequence != want {
+			t.Fatalf("got publish response %v, want sequence %d", response, want)
+		}
+	}
+}
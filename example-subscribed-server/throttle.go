@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	pb "handle-subscribed/protobuf"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// Throttle holds the ticks of a client that wait for the next flush, at most
+// one flush is sent to the client per interval
+type Throttle struct {
+	interval time.Duration
+	mode     pb.ConflationMode
+
+	// The pending ticks, in latest mode only the last tick of each channel is
+	// kept and skipped counts the ticks it replaced
+	ticks   []*pb.Tick
+	skipped map[string]int
+
+	lastFlush time.Time
+	scheduled bool
+}
+
+// Check the throttle options of a subscription request, an interval of 0 with
+// no conflation mode removes the throttle of the client
+func validateThrottleOptions(options *pb.ThrottleOptions) error {
+	if options.MinIntervalMs == 0 && options.Mode != pb.ConflationMode_NONE {
+		return errors.New("throttle needs a min_interval_ms")
+	}
+
+	if options.MinIntervalMs != 0 && options.Mode == pb.ConflationMode_NONE {
+		return errors.New("throttle needs a conflation mode")
+	}
+
+	if options.Mode != pb.ConflationMode_NONE && options.Mode != pb.ConflationMode_LATEST && options.Mode != pb.ConflationMode_BATCH {
+		return errors.New("unknown conflation mode")
+	}
+
+	return nil
+}
+
+// Create a throttle from validated options
+func newThrottle(options *pb.ThrottleOptions) *Throttle {
+	return &Throttle{
+		interval: time.Duration(options.MinIntervalMs) * time.Millisecond,
+		mode:     options.Mode,
+		skipped:  make(map[string]int),
+	}
+}
+
+// Keep the tick until the next flush, in latest mode it replaces the pending
+// tick of its channel
+func (throttle *Throttle) add(tick *pb.Tick) {
+	if throttle.mode == pb.ConflationMode_LATEST {
+		for i, pending := range throttle.ticks {
+			if pending.Channel == tick.Channel {
+				throttle.ticks[i] = tick
+				throttle.skipped[tick.Channel]++
+				return
+			}
+		}
+	}
+
+	throttle.ticks = append(throttle.ticks, tick)
+}
+
+// Get the time until the next flush is allowed, 0 if it is allowed now
+func (throttle *Throttle) wait(now time.Time) time.Duration {
+	wait := throttle.lastFlush.Add(throttle.interval).Sub(now)
+	if wait < 0 {
+		return 0
+	}
+
+	return wait
+}
+
+// Get the pending ticks and reset the throttle, in latest mode a tick that
+// replaced others is a copy marked as conflated
+func (throttle *Throttle) flush(now time.Time) []*pb.Tick {
+	throttle.lastFlush = now
+
+	var ticks = throttle.ticks
+	var skipped = throttle.skipped
+	throttle.ticks = nil
+	throttle.skipped = make(map[string]int)
+
+	for i, tick := range ticks {
+		// The history keeps the original tick, mark a copy as conflated
+		if skipped[tick.Channel] > 0 {
+			tick = proto.Clone(tick).(*pb.Tick)
+			tick.Conflated = true
+			ticks[i] = tick
+		}
+	}
+
+	return ticks
+}
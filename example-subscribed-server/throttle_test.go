@@ -0,0 +1,116 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	pb "handle-subscribed/protobuf"
+)
+
+// Subscribe the test client to the channels with a throttle of the interval
+// and conflation mode
+func subscribeThrottled(t *testing.T, client *TestClient, interval time.Duration, mode pb.ConflationMode, channels ...string) {
+	t.Helper()
+
+	client.send(&pb.ClientMessage{
+		Payload: &pb.ClientMessage_SubscriptionRequest{
+			SubscriptionRequest: &pb.SubscriptionRequest{
+				RequestId: "1",
+				Action:    pb.SubscriptionAction_SUBSCRIBE,
+				Channels:  channels,
+				Throttle:  &pb.ThrottleOptions{MinIntervalMs: uint32(interval / time.Millisecond), Mode: mode},
+			},
+		},
+	})
+
+	if response := client.read().GetSubscriptionResponse(); response == nil || !response.Success {
+		t.Fatalf("got %v, want a successful subscription response", response)
+	}
+}
+
+// Broadcast a tick with the value to the channel like a publisher does
+func broadcastTestTick(server *WebSocketServer, channel string, value float64) {
+	tick := newTick(channel)
+	tick.Value = &pb.Tick_DoubleValue{DoubleValue: value}
+	server.broadcast <- []*pb.Tick{tick}
+}
+
+func TestThrottleLatestConflation(t *testing.T) {
+	clock := newManualClock(clockTestStart)
+	server := newTestServer(&Channel{Name: "prices.btc"}, &Channel{Name: "prices.eth"})
+	server.clock = clock
+
+	testServer := startTestServer(t, server)
+	client := dialTestClient(t, testServer)
+	subscribeThrottled(t, client, time.Second, pb.ConflationMode_LATEST, "prices.*")
+
+	// The first tick is sent right away since nothing was flushed yet
+	broadcastTestTick(server, "prices.btc", 1)
+	if tick := client.readTick(); tick.GetDoubleValue() != 1 || tick.Conflated {
+		t.Fatalf("got %v, want the first btc tick unconflated", tick)
+	}
+
+	// The ticks of the next second wait for the flush, only the latest of
+	// each channel is kept
+	broadcastTestTick(server, "prices.btc", 2)
+	broadcastTestTick(server, "prices.eth", 10)
+	broadcastTestTick(server, "prices.btc", 3)
+
+	waitForWaiters(t, clock, 1)
+	clock.advance(time.Second)
+
+	btc := client.readTick()
+	if btc.Channel != "prices.btc" || btc.GetDoubleValue() != 3 || btc.Sequence != 3 || !btc.Conflated {
+		t.Errorf("got %v, want the third btc tick marked as conflated", btc)
+	}
+
+	eth := client.readTick()
+	if eth.Channel != "prices.eth" || eth.GetDoubleValue() != 10 || eth.Conflated {
+		t.Errorf("got %v, want the eth tick unconflated", eth)
+	}
+
+	// The flushed ticks are counted like broadcast ticks
+	waitForMetric(t, testServer, `subscribed_server_messages_sent_total{type="tick"}`, 3)
+}
+
+func TestThrottleBatchConflation(t *testing.T) {
+	clock := newManualClock(clockTestStart)
+	server := newTestServer(&Channel{Name: "prices.btc"}, &Channel{Name: "prices.eth"})
+	server.clock = clock
+
+	testServer := startTestServer(t, server)
+	client := dialTestClient(t, testServer)
+	subscribeThrottled(t, client, time.Second, pb.ConflationMode_BATCH, "prices.*")
+
+	broadcastTestTick(server, "prices.btc", 1)
+	if batch := client.read().GetTickBatch(); batch == nil || len(batch.Ticks) != 1 || batch.Ticks[0].GetDoubleValue() != 1 {
+		t.Fatalf("got %v, want a batch of the first btc tick", batch)
+	}
+
+	// Every tick of the next second is sent in one batch in publish order
+	broadcastTestTick(server, "prices.btc", 2)
+	broadcastTestTick(server, "prices.eth", 10)
+	broadcastTestTick(server, "prices.btc", 3)
+
+	waitForWaiters(t, clock, 1)
+	clock.advance(time.Second)
+
+	batch := client.read().GetTickBatch()
+	if batch == nil {
+		t.Fatal("got no batch after the interval")
+	}
+
+	var values []float64
+	for _, tick := range batch.Ticks {
+		if tick.Conflated {
+			t.Errorf("batched tick %v is marked as conflated", tick)
+		}
+		values = append(values, tick.GetDoubleValue())
+	}
+
+	if len(values) != 3 || values[0] != 2 || values[1] != 10 || values[2] != 3 {
+		t.Errorf("got a batch of the values %v, want [2 10 3]", values)
+	}
+
+	waitForMetric(t, testServer, `subscribed_server_messages_sent_total{type="tick"}`, 4)
+}
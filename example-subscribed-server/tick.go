@@ -0,0 +1,47 @@
+package main
+
+import (
+	"math"
+
+	pb "handle-subscribed/protobuf"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// Create a fixed-point decimal of the value rounded to scale digits
+func newDecimal(value float64, scale int32) *pb.Decimal {
+	return &pb.Decimal{
+		Units: int64(math.Round(value * math.Pow10(int(scale)))),
+		Scale: scale,
+	}
+}
+
+// Create a new tick for the channel, the timestamp and sequence number are
+// stamped when it is published
+func newTick(channel string) *pb.Tick {
+	return &pb.Tick{
+		Channel: channel,
+	}
+}
+
+// Define a function to convert a Tick to a byte slice
+func marshalTick(tick *pb.Tick) ([]byte, error) {
+	var wrappedMessage = &pb.WebSocketMessage{
+		Paylod: &pb.WebSocketMessage_Tick{
+			Tick: tick,
+		},
+	}
+
+	return proto.Marshal(wrappedMessage)
+}
+
+// Define a function to convert ticks to a byte slice of one TickBatch
+func marshalTickBatch(ticks []*pb.Tick) ([]byte, error) {
+	var wrappedMessage = &pb.WebSocketMessage{
+		Paylod: &pb.WebSocketMessage_TickBatch{
+			TickBatch: &pb.TickBatch{Ticks: ticks},
+		},
+	}
+
+	return proto.Marshal(wrappedMessage)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The wildcard tokens of a subscription pattern, '*' matches exactly one
+// token and '>' matches one or more tokens at the end of a topic
+const (
+	singleWildcard = "*"
+	tailWildcard   = ">"
+)
+
+// TopicNode is a node of the topic trie, one per token of a pattern
+type TopicNode struct {
+	children    map[string]*TopicNode
+	subscribers map[*Client]bool
+}
+
+// TopicTrie holds the subscription patterns of all clients indexed by token,
+// so the subscribers of a topic are found without looking at every client
+type TopicTrie struct {
+	root *TopicNode
+}
+
+// Create a new empty topic trie
+func newTopicTrie() *TopicTrie {
+	return &TopicTrie{root: newTopicNode()}
+}
+
+// Create a new empty topic node
+func newTopicNode() *TopicNode {
+	return &TopicNode{
+		children:    make(map[string]*TopicNode),
+		subscribers: make(map[*Client]bool),
+	}
+}
+
+// Check that a subscription pattern is a valid channel name where tokens may
+// be replaced by '*' and the last token may be '>'
+func validatePattern(pattern string) error {
+	tokens := strings.Split(pattern, ".")
+	for i, token := range tokens {
+		switch {
+		case token == singleWildcard:
+		case token == tailWildcard:
+			if i != len(tokens)-1 {
+				return fmt.Errorf("invalid pattern %q: %q must be the last token", pattern, tailWildcard)
+			}
+		case !channelNamePattern.MatchString(token):
+			return fmt.Errorf("invalid pattern %q", pattern)
+		}
+	}
+
+	return nil
+}
+
+// Check if the pattern contains a wildcard token
+func isWildcardPattern(pattern string) bool {
+	for _, token := range strings.Split(pattern, ".") {
+		if token == singleWildcard || token == tailWildcard {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check if the pattern matches the topic
+func patternMatches(pattern string, topic string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	topicTokens := strings.Split(topic, ".")
+
+	for i, token := range patternTokens {
+		if token == tailWildcard {
+			return len(topicTokens) > i
+		}
+
+		if i >= len(topicTokens) || (token != singleWildcard && token != topicTokens[i]) {
+			return false
+		}
+	}
+
+	return len(patternTokens) == len(topicTokens)
+}
+
+// Check if every topic the pattern matches is also matched by the covering
+// pattern, a '*' covers any single token but not a '>'
+func patternCovers(covering string, pattern string) bool {
+	coveringTokens := strings.Split(covering, ".")
+	patternTokens := strings.Split(pattern, ".")
+
+	for i, token := range coveringTokens {
+		if token == tailWildcard {
+			return len(patternTokens) > i
+		}
+
+		if i >= len(patternTokens) || patternTokens[i] == tailWildcard {
+			return false
+		}
+
+		if token != singleWildcard && token != patternTokens[i] {
+			return false
+		}
+	}
+
+	return len(coveringTokens) == len(patternTokens)
+}
+
+// Add the client as a subscriber of the pattern
+func (trie *TopicTrie) insert(pattern string, client *Client) {
+	node := trie.root
+	for _, token := range strings.Split(pattern, ".") {
+		child, ok := node.children[token]
+		if !ok {
+			child = newTopicNode()
+			node.children[token] = child
+		}
+
+		node = child
+	}
+
+	node.subscribers[client] = true
+}
+
+// Remove the client as a subscriber of the pattern, nodes that are left
+// without subscribers and children are pruned
+func (trie *TopicTrie) remove(pattern string, client *Client) {
+	trie.root.remove(strings.Split(pattern, "."), client)
+}
+
+// Remove the client from the node of the tokens below this node and report
+// whether this node is empty afterwards
+func (node *TopicNode) remove(tokens []string, client *Client) bool {
+	if len(tokens) == 0 {
+		delete(node.subscribers, client)
+	} else if child, ok := node.children[tokens[0]]; ok {
+		if child.remove(tokens[1:], client) {
+			delete(node.children, tokens[0])
+		}
+	}
+
+	return len(node.subscribers) == 0 && len(node.children) == 0
+}
+
+// Get the clients with a pattern that matches the topic, a client with
+// several matching patterns is only returned once
+func (trie *TopicTrie) match(topic string) map[*Client]bool {
+	var clients = make(map[*Client]bool)
+	trie.root.match(strings.Split(topic, "."), clients)
+
+	return clients
+}
+
+// Collect the subscribers of the patterns below this node that match the tokens
+func (node *TopicNode) match(tokens []string, clients map[*Client]bool) {
+	if len(tokens) == 0 {
+		for client := range node.subscribers {
+			clients[client] = true
+		}
+		return
+	}
+
+	if child, ok := node.children[tailWildcard]; ok {
+		for client := range child.subscribers {
+			clients[client] = true
+		}
+	}
+
+	if child, ok := node.children[singleWildcard]; ok {
+		child.match(tokens[1:], clients)
+	}
+
+	if child, ok := node.children[tokens[0]]; ok {
+		child.match(tokens[1:], clients)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestTopicTrieMatch(t *testing.T) {
+	// Each client subscribes to its patterns, a client with several patterns
+	// that match a topic must be returned once
+	subscriptions := map[string][]string{
+		"exact":    {"prices.btc"},
+		"single":   {"prices.*"},
+		"tail":     {"prices.>"},
+		"deep":     {"prices.*.usd"},
+		"all":      {">"},
+		"overlap":  {"prices.btc", "prices.*", "prices.>", "*.btc"},
+		"news":     {"news"},
+		"nested":   {"prices.btc.>"},
+		"wildcard": {"*"},
+	}
+
+	trie := newTopicTrie()
+	var clients = make(map[*Client]string)
+	for name, patterns := range subscriptions {
+		client := &Client{}
+		clients[client] = name
+		for _, pattern := range patterns {
+			trie.insert(pattern, client)
+		}
+	}
+
+	tests := []struct {
+		topic string
+		want  []string
+	}{
+		{topic: "prices.btc", want: []string{"all", "exact", "overlap", "single", "tail"}},
+		{topic: "prices.eth", want: []string{"all", "overlap", "single", "tail"}},
+		{topic: "prices.btc.usd", want: []string{"all", "deep", "nested", "overlap", "tail"}},
+		{topic: "prices.eth.usd", want: []string{"all", "deep", "overlap", "tail"}},
+		{topic: "prices.btc.usd.spot", want: []string{"all", "nested", "overlap", "tail"}},
+		{topic: "stocks.btc", want: []string{"all", "overlap"}},
+		{topic: "prices", want: []string{"all", "wildcard"}},
+		{topic: "news", want: []string{"all", "news", "wildcard"}},
+		{topic: "news.today", want: []string{"all"}},
+	}
+
+	for _, test := range tests {
+		matched := trie.match(test.topic)
+
+		var got []string
+		for client := range matched {
+			got = append(got, clients[client])
+		}
+		sort.Strings(got)
+
+		if strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("match(%q) = %v, want %v", test.topic, got, test.want)
+		}
+
+		// The trie agrees with matching every pattern on its own
+		for client, name := range clients {
+			var matches bool
+			for _, pattern := range subscriptions[name] {
+				matches = matches || patternMatches(pattern, test.topic)
+			}
+
+			if matches != matched[client] {
+				t.Errorf("%s: match(%q) = %v, patternMatches = %v", name, test.topic, matched[client], matches)
+			}
+		}
+	}
+
+	// A topic nobody subscribed to matches nothing once the catch-alls leave
+	for client, name := range clients {
+		if name == "all" || name == "wildcard" {
+			trie.remove(subscriptions[name][0], client)
+		}
+	}
+	if matched := trie.match("weather"); len(matched) != 0 {
+		t.Errorf("match(\"weather\") returned %d clients, want none", len(matched))
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	tests := map[string]bool{
+		"prices":         true,
+		"prices.btc":     true,
+		"prices.*":       true,
+		"prices.>":       true,
+		"*.btc.*":        true,
+		">":              true,
+		"*":              true,
+		"prices.>.btc":   false,
+		"prices..btc":    false,
+		"prices.":        false,
+		".prices":        false,
+		"prices.b*":      false,
+		"prices.btc usd": false,
+		"":               false,
+	}
+
+	for pattern, valid := range tests {
+		if err := validatePattern(pattern); (err == nil) != valid {
+			t.Errorf("validatePattern(%q) = %v, want valid %v", pattern, err, valid)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+// TopicIndex maps channels to their subscribers. The clients are spread over
+// shards that each keep a topic trie of their own clients, a broadcast is
+// matched and delivered by every shard in its own goroutine so a channel with
+// thousands of subscribers uses every core. The index is only changed by the
+// run goroutine and a broadcast blocks until every shard is done, so the
+// shards never read a trie while it is changed
+type TopicIndex struct {
+	shards  []*TopicShard
+	clients map[*Client]*TopicShard
+	next    int
+}
+
+// TopicShard is the part of the index with the subscriptions of some clients
+type TopicShard struct {
+	topics *TopicTrie
+	jobs   chan *FanOutJob
+}
+
+// FanOutJob is a broadcast to the subscribers of a topic in one shard
+type FanOutJob struct {
+	topic   string
+	deliver func(client *Client)
+	done    *sync.WaitGroup
+}
+
+// Create a new empty topic index with the number of shards and start their
+// goroutines, 0 shards uses one shard per CPU
+func newTopicIndex(shards int) *TopicIndex {
+	if shards <= 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+
+	var index = &TopicIndex{clients: make(map[*Client]*TopicShard)}
+	for i := 0; i < shards; i++ {
+		shard := &TopicShard{
+			topics: newTopicTrie(),
+			jobs:   make(chan *FanOutJob),
+		}
+
+		index.shards = append(index.shards, shard)
+		go shard.run()
+	}
+
+	return index
+}
+
+// This a goroutine that delivers the broadcasts of a shard
+func (shard *TopicShard) run() {
+	for job := range shard.jobs {
+		for client := range shard.topics.match(job.topic) {
+			job.deliver(client)
+		}
+
+		job.done.Done()
+	}
+}
+
+// Get the shard of the client, a new client is given the next shard in turn
+func (index *TopicIndex) shard(client *Client) *TopicShard {
+	shard, ok := index.clients[client]
+	if !ok {
+		shard = index.shards[index.next]
+		index.next = (index.next + 1) % len(index.shards)
+		index.clients[client] = shard
+	}
+
+	return shard
+}
+
+// Add the client as a subscriber of the pattern
+func (index *TopicIndex) insert(pattern string, client *Client) {
+	index.shard(client).topics.insert(pattern, client)
+}
+
+// Remove the client as a subscriber of the pattern
+func (index *TopicIndex) remove(pattern string, client *Client) {
+	if shard, ok := index.clients[client]; ok {
+		shard.topics.remove(pattern, client)
+	}
+}
+
+// Remove the client and its subscriptions to the patterns from the index
+func (index *TopicIndex) removeClient(client *Client, patterns map[string]bool) {
+	for pattern := range patterns {
+		index.remove(pattern, client)
+	}
+
+	delete(index.clients, client)
+}
+
+// Get the number of clients with a pattern that matches the topic
+func (index *TopicIndex) count(topic string) int {
+	var count int
+	for _, shard := range index.shards {
+		count += len(shard.topics.match(topic))
+	}
+
+	return count
+}
+
+// Call deliver with every client that has a pattern matching the topic and
+// wait until all of them are done, deliver is called from the goroutines of
+// the shards at the same time but never twice at once for the same client
+func (index *TopicIndex) fanOut(topic string, deliver func(client *Client)) {
+	var done sync.WaitGroup
+	done.Add(len(index.shards))
+
+	for _, shard := range index.shards {
+		shard.jobs <- &FanOutJob{topic: topic, deliver: deliver, done: &done}
+	}
+
+	done.Wait()
+}
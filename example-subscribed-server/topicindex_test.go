@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+// Check that the trie has no nodes left below its root
+func checkEmptyTrie(t *testing.T, trie *TopicTrie) {
+	t.Helper()
+
+	if len(trie.root.children) != 0 || len(trie.root.subscribers) != 0 {
+		t.Fatalf("trie still has the nodes %v", trie.root.children)
+	}
+}
+
+func TestTopicTrieRemovePrunes(t *testing.T) {
+	trie := newTopicTrie()
+	first, second := &Client{}, &Client{}
+
+	trie.insert("prices.btc.usd", first)
+	trie.insert("prices.btc.*", first)
+	trie.insert("prices.btc.usd", second)
+
+	// A node with another subscriber is kept
+	trie.remove("prices.btc.usd", first)
+	btc := trie.root.children["prices"].children["btc"]
+	if usd, ok := btc.children["usd"]; !ok || len(usd.subscribers) != 1 || !usd.subscribers[second] {
+		t.Fatalf("prices.btc.usd was pruned while the second client is subscribed")
+	}
+
+	// A node with children is kept when its last subscriber leaves, and
+	// removing a pattern that was never inserted changes nothing
+	trie.remove("prices.btc.usd", second)
+	trie.remove("prices.eth", first)
+	if _, ok := btc.children["usd"]; ok {
+		t.Fatal("prices.btc.usd was kept without subscribers")
+	}
+	if _, ok := btc.children[singleWildcard]; !ok {
+		t.Fatal("prices.btc.* was pruned while the first client is subscribed")
+	}
+
+	// The last pattern takes its whole branch with it
+	trie.remove("prices.btc.*", first)
+	checkEmptyTrie(t, trie)
+}
+
+func TestTopicIndexRemoveClientPrunes(t *testing.T) {
+	index := newTopicIndex(2)
+	first, second := &Client{}, &Client{}
+
+	// The clients are on different shards
+	var patterns = map[string]bool{"prices.>": true, "prices.btc": true, "news.*": true}
+	for pattern := range patterns {
+		index.insert(pattern, first)
+	}
+	index.insert("prices.btc", second)
+
+	if got := index.count("prices.btc"); got != 2 {
+		t.Fatalf("prices.btc has %d subscribers, want 2", got)
+	}
+
+	index.remove("news.*", first)
+	if index.clients[first].topics.root.children["news"] != nil {
+		t.Fatal("news.* was kept after it was removed")
+	}
+
+	index.removeClient(first, patterns)
+	if _, ok := index.clients[first]; ok {
+		t.Fatal("the removed client still has a shard")
+	}
+
+	if got := index.count("prices.btc"); got != 1 {
+		t.Fatalf("prices.btc has %d subscribers after the first client left, want 1", got)
+	}
+
+	index.removeClient(second, map[string]bool{"prices.btc": true})
+	for _, shard := range index.shards {
+		checkEmptyTrie(t, shard.topics)
+	}
+}
+
+// The number of clients of the broadcast benchmark and the channels they
+// subscribe to, every tenth client subscribes to all of them with a wildcard
+const (
+	benchmarkClients  = 10000
+	benchmarkChannels = 100
+)
+
+// Create the clients of the broadcast benchmark with their patterns, each
+// client has a goroutine that drains its queue like writeMessages would
+func newBenchmarkClients(b *testing.B) map[*Client]map[string]bool {
+	var metrics = newMetrics()
+	var logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var clients = make(map[*Client]map[string]bool)
+	for i := 0; i < benchmarkClients; i++ {
+		client := &Client{send: make(chan *OutgoingMessage, clientSendBufferSize), metrics: metrics, logger: logger}
+		go func() {
+			for range client.send {
+			}
+		}()
+		b.Cleanup(func() { close(client.send) })
+
+		clients[client] = map[string]bool{fmt.Sprintf("bench.%d", i%benchmarkChannels): true}
+		if i%10 == 0 {
+			clients[client]["bench.>"] = true
+		}
+	}
+
+	return clients
+}
+
+// Compare sending a tick to the subscribers of its channel by checking the
+// patterns of every client, by matching one topic trie and by the shards of
+// the topic index
+func BenchmarkBroadcast(b *testing.B) {
+	clients := newBenchmarkClients(b)
+	frame := testTickFrame(b, 1)
+
+	var topics = make([]string, benchmarkChannels)
+	for i := range topics {
+		topics[i] = fmt.Sprintf("bench.%d", i)
+	}
+
+	b.Run("loop", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			topic := topics[i%len(topics)]
+			for client, patterns := range clients {
+				for pattern := range patterns {
+					if patternMatches(pattern, topic) {
+						client.sendTickMessage(frame)
+						break
+					}
+				}
+			}
+		}
+	})
+
+	b.Run("trie", func(b *testing.B) {
+		trie := newTopicTrie()
+		for client, patterns := range clients {
+			for pattern := range patterns {
+				trie.insert(pattern, client)
+			}
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for client := range trie.match(topics[i%len(topics)]) {
+				client.sendTickMessage(frame)
+			}
+		}
+	})
+
+	b.Run("sharded", func(b *testing.B) {
+		index := newTopicIndex(0)
+		for client, patterns := range clients {
+			for pattern := range patterns {
+				index.insert(pattern, client)
+			}
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			index.fanOut(topics[i%len(topics)], func(client *Client) {
+				client.sendTickMessage(frame)
+			})
+		}
+	})
+}
@@ -1,14 +1,32 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	pb "server/pokemon"
+	"shared/auth"
+	"shared/config"
+	"shared/logging"
+	"shared/origin"
+	"shared/ratelimit"
+	"shared/tlsconfig"
+	"shared/tracing"
 )
 
 // Define a global list of Pokemon
@@ -27,10 +45,39 @@ var pokemonList = &pb.PokemonList{
 	},
 }
 
-// Define a struct to hold the WebSocket connections
+// Define a struct to hold the WebSocket connections, who they
+// authenticated as, the metrics their messages are counted in and the logger
+// of their lines, requests is the id of the last message read
 type Connection struct {
-	ws   *websocket.Conn
-	send chan []byte
+	ws       *websocket.Conn
+	identity *auth.Identity
+	send     chan []byte
+	metrics  *Metrics
+	logger   *slog.Logger
+	requests int64
+}
+
+// Define a struct to hold the open connections, they are added and removed by
+// the goroutines of the connections
+type ConnectionSet struct {
+	mutex       sync.Mutex
+	connections map[*Connection]bool
+}
+
+// Define a method to add a connection to the set
+func (set *ConnectionSet) add(conn *Connection) {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+
+	set.connections[conn] = true
+}
+
+// Define a method to remove a connection from the set
+func (set *ConnectionSet) remove(conn *Connection) {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+
+	delete(set.connections, conn)
 }
 
 // Define a method to send a message
@@ -38,7 +85,7 @@ func (conn *Connection) handleOutgoingMessage() {
 	for message := range conn.send {
 		err := conn.ws.WriteMessage(websocket.BinaryMessage, message)
 		if err != nil {
-			log.Println("Error writing message to WebSocket:", err)
+			conn.logger.Warn("Error writing message to WebSocket", "error", err)
 			break
 		}
 	}
@@ -57,9 +104,25 @@ func (conn *Connection) sendInitialData() {
 
 	err := conn.ws.WriteMessage(websocket.TextMessage, []byte(serverMessage))
 	if err != nil {
-		log.Println("Error writing message to WebSocket:", err)
+		conn.logger.Warn("Error writing message to WebSocket", "error", err)
 		return
 	}
+	conn.metrics.messagesSent.WithLabelValues("text").Inc()
+}
+
+// Define a method to send a message of the type and count it
+func (conn *Connection) sendMessage(messageType string, message []byte) {
+	conn.metrics.messagesSent.WithLabelValues(messageType).Inc()
+	conn.send <- message
+}
+
+// Define a method to send an error message for the last request and count
+// it by its code
+func (conn *Connection) sendError(message string, errorCode pb.ErrorCode) {
+	errMsg, _ := marshalErrorMessage(message, errorCode)
+	conn.metrics.errors.WithLabelValues(errorCodeLabel(errorCode)).Inc()
+	conn.logger.Info("Query failed", "request_id", conn.requests, "code", errorCodeLabel(errorCode), "message", message)
+	conn.sendMessage("error_message", errMsg)
 }
 
 // Define a function to convert a PokemonList to a byte slice
@@ -74,7 +137,7 @@ func marshalPokemonList(pl *pb.PokemonList) ([]byte, error) {
 }
 
 // Define a function to convert an error message to a byte slice
-func marshalErrorMessage(message string, errorCode int32) ([]byte, error) {
+func marshalErrorMessage(message string, errorCode pb.ErrorCode) ([]byte, error) {
 	var wrappedMessage = &pb.WebSocketMessage{
 		Paylod: &pb.WebSocketMessage_ErrorMessage{
 			ErrorMessage: &pb.ErrorMessage{
@@ -87,18 +150,48 @@ func marshalErrorMessage(message string, errorCode int32) ([]byte, error) {
 	return proto.Marshal(wrappedMessage)
 }
 
+// Define a function to find the pokemon matching the query in the store, a
+// query without an id, name or region matches all of them
+func searchPokemon(ctx context.Context, query *pb.PokemonQuery) *pb.PokemonList {
+	_, span := otel.Tracer(tracing.TracerName).Start(ctx, "store query")
+	defer span.End()
+
+	if query.Id == "" && query.Name == "" && query.Region == "" {
+		return pokemonList
+	}
+
+	var results = &pb.PokemonList{}
+	for _, p := range pokemonList.Pokemon {
+		if (query.Id != "" && p.Id == query.Id) || (query.Name != "" && p.Name == query.Name) || (query.Region != "" && p.Region == query.Region) {
+			results.Pokemon = append(results.Pokemon, p)
+		}
+	}
+
+	return results
+}
+
 // Define a function to handle WebSocket connections
-func handleConnection(ws *websocket.Conn, connections map[*Connection]bool) {
+func handleConnection(ws *websocket.Conn, identity *auth.Identity, authorizer *Authorizer, limits *ratelimit.Connection, metrics *Metrics, connections *ConnectionSet) {
 	// Create a new connection
 	conn := &Connection{
-		ws:   ws,
-		send: make(chan []byte),
+		ws:       ws,
+		identity: identity,
+		send:     make(chan []byte),
+		metrics:  metrics,
+		logger:   logging.ConnectionLogger(ws.RemoteAddr().String(), identity.Subject),
 	}
-	connections[conn] = true
+	connections.add(conn)
+	metrics.connections.Inc()
+	defer metrics.connections.Dec()
+
+	conn.logger.Info("New connection established", "auth_method", identity.Method)
+	defer conn.logger.Info("Connection closed")
 
 	// Send initial data to the client
 	conn.sendInitialData()
 
+	tracer := otel.Tracer(tracing.TracerName)
+
 	go conn.handleOutgoingMessage()
 
 	// Listen for incoming messages from client
@@ -106,87 +199,281 @@ func handleConnection(ws *websocket.Conn, connections map[*Connection]bool) {
 		_, message, err := ws.ReadMessage()
 		if err != nil {
 			// Remove the connection from the list and close the WebSocket
-			delete(connections, conn)
+			connections.remove(conn)
+			close(conn.send)
+			return
+		}
+		conn.requests++
+
+		// Drop the queries over the rate limit and close the connection of a
+		// client that keeps going over it
+		allowed, abusive := limits.Allow()
+		if abusive {
+			conn.logger.Warn("Closing connection for sending too many queries")
+			ratelimit.ClosePolicyViolation(ws, "too many messages")
+			connections.remove(conn)
 			close(conn.send)
 			return
 		}
 
+		if !allowed {
+			metrics.messagesDropped.WithLabelValues(dropRateLimited).Inc()
+			conn.sendError("rate limited", pb.ErrorCode_RATE_LIMITED)
+			continue
+		}
+
+		// Decode the query, its span continues the trace of the client and
+		// starts when decoding started
+		decodeStart := time.Now()
 		var query = &pb.PokemonQuery{}
 		if err := proto.Unmarshal(message, query); err != nil {
-			fmt.Println("Error unmarshaling query:", err)
-			errMsg, _ := marshalErrorMessage("unknow command query", 2)
-			conn.send <- errMsg
+			conn.logger.Warn("Error unmarshaling query", "request_id", conn.requests, "error", err)
+			metrics.messagesDropped.WithLabelValues(dropInvalid).Inc()
+			conn.sendError("unknow command query", pb.ErrorCode_INVALID_REQUEST)
 			continue
 		}
+		decodeEnd := time.Now()
+
+		ctx, span := tracer.Start(tracing.ExtractTraceparent(context.Background(), query.Traceparent), "PokemonQuery",
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithTimestamp(decodeStart),
+			trace.WithAttributes(tracing.QueryAttributes(query)...),
+			trace.WithAttributes(attribute.String("user", conn.identity.Subject)),
+		)
+		_, decodeSpan := tracer.Start(ctx, "decode", trace.WithTimestamp(decodeStart))
+		decodeSpan.End(trace.WithTimestamp(decodeEnd))
+
+		metrics.messagesReceived.WithLabelValues("pokemon_query").Inc()
+		received := time.Now()
+		conn.logger.Debug("Received query", "request_id", conn.requests, "trace_id", span.SpanContext().TraceID().String(), "query", queryLabel(query), "id", query.Id, "name", query.Name, "region", query.Region)
 
-		if query.Id == "" && query.Name == "" && query.Region == "" {
-			// If no query is specified, return the full Pokemon list
-			pokemonListBytes, err := marshalPokemonList(pokemonList)
-			if err != nil {
-				errMsg, _ := marshalErrorMessage("failed to marshal PokemonList", 1)
-				conn.send <- errMsg
-				return
-			}
-
-			conn.send <- pokemonListBytes
-		} else {
-			// Filter the Pokemon list based on the query
-			var results = &pb.PokemonList{}
-			for _, p := range pokemonList.Pokemon {
-				if (query.Id != "" && p.Id == query.Id) || (query.Name != "" && p.Name == query.Name) || (query.Region != "" && p.Region == query.Region) {
-					results.Pokemon = append(results.Pokemon, p)
-				}
-			}
-
-			pokemonListBytes, err := marshalPokemonList(results)
-			if err != nil {
-				errMsg, _ := marshalErrorMessage("failed to marshal PokemonList", 1)
-				conn.send <- errMsg
-				return
-			}
-
-			conn.send <- pokemonListBytes
+		// Check the query against the policies before running it
+		if operation := queryOperationOf(query); !authorizer.allowed(conn.identity, operation) {
+			conn.sendError(fmt.Sprintf("%s is not allowed to %s", conn.identity.Subject, operation), pb.ErrorCode_UNAUTHORIZED)
+			span.SetStatus(codes.Error, "unauthorized")
+			span.End()
+			continue
 		}
+
+		results := searchPokemon(ctx, query)
+
+		_, encodeSpan := tracer.Start(ctx, "encode")
+		pokemonListBytes, err := marshalPokemonList(results)
+		encodeSpan.End()
+		if err != nil {
+			conn.sendError("failed to marshal PokemonList", pb.ErrorCode_INTERNAL)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "failed to marshal PokemonList")
+			span.End()
+			return
+		}
+
+		conn.sendMessage("pokemon_list", pokemonListBytes)
+		span.SetAttributes(attribute.Int("pokemon.results", len(results.Pokemon)))
+		span.End()
+
+		duration := time.Since(received)
+		metrics.queryDuration.WithLabelValues(queryLabel(query)).Observe(duration.Seconds())
+		conn.logger.Debug("Handled query", "request_id", conn.requests, "query", queryLabel(query), "duration", duration)
 	}
 }
 
-func main() {
-	// Create a map to hold the WebSocket connections
-	connections := make(map[*Connection]bool)
+// Define a function to create the HTTP handler of the WebSocket connections,
+// each upgrade is authenticated and counted for its IP address before it is
+// upgraded
+func webSocketHandler(upgrader *websocket.Upgrader, authenticator auth.Authenticator, authorizer *Authorizer, rateLimiter *ratelimit.Limiter, metrics *Metrics) http.HandlerFunc {
+	// Create a set to hold the WebSocket connections
+	connections := &ConnectionSet{connections: make(map[*Connection]bool)}
 
-	// Define a WebSocket upgrade handler
-	upgrader := websocket.Upgrader{
-		ReadBufferSize:  1024,
-		WriteBufferSize: 1024,
-		CheckOrigin: func(r *http.Request) bool {
-			return true
-		},
-	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Check the bearer token before upgrading, a rejected client gets a 401
+		identity := auth.AuthenticateUpgrade(authenticator, w, r)
+		if identity == nil {
+			return
+		}
 
-	// Define an HTTP handle to handle root path requests
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintf(w, "Hello, this is the Pokemon WebSocket server!")
-	})
+		// Refuse the connection if its IP address has too many already
+		limits := rateLimiter.Connect(ratelimit.RemoteIP(r))
+		if limits == nil {
+			slog.Warn("Rejected connection, too many connections from its IP address", "remote_addr", r.RemoteAddr, "user", identity.Subject)
+			http.Error(w, "too many connections", http.StatusTooManyRequests)
+			return
+		}
+		defer limits.Close()
 
-	// Define an HTTP handler function to handle WebSocket connections
-	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
 		// Upgrade the connection to a WebSocket connection
 		ws, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
-			log.Println("Error upgrading connection to WebSocket:", err)
+			slog.Warn("Error upgrading connection to WebSocket", "remote_addr", r.RemoteAddr, "user", identity.Subject, "error", err)
 			return
 		}
 
-		fmt.Println("New connection established", ws.RemoteAddr().String())
+		// A message larger than the limit closes the connection
+		if maxMessageSize := rateLimiter.Options().MaxMessageSize; maxMessageSize > 0 {
+			ws.SetReadLimit(maxMessageSize)
+		}
 
 		// Handle the WebSocket connection
-		handleConnection(ws, connections)
+		handleConnection(ws, identity, authorizer, limits, metrics, connections)
+	}
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "address the server listens on")
+	wsPath := flag.String("ws-path", "/ws", "path of the websocket endpoint")
+	metricsPath := flag.String("metrics-path", "/metrics", "path of the Prometheus metrics endpoint, empty to disable it")
+	readBufferSize := flag.Int("read-buffer-size", 1024, "size in bytes of the websocket read buffer")
+	writeBufferSize := flag.Int("write-buffer-size", 1024, "size in bytes of the websocket write buffer")
+	authTokens := flag.String("auth-tokens", "", "path to a file of bearer tokens and their subjects, one per line")
+	authJWTKey := flag.String("auth-jwt-key", "", "path to the HMAC key of the HS256 JWTs accepted as bearer tokens")
+	issueToken := flag.String("issue-token", "", "print a JWT for the subject signed with -auth-jwt-key and exit")
+	issueTokenTTL := flag.Duration("issue-token-ttl", 24*time.Hour, "how long a JWT printed by -issue-token is valid")
+	tlsCert := flag.String("tls-cert", "", "path to the PEM certificate of the server, serves wss:// with -tls-key")
+	tlsKey := flag.String("tls-key", "", "path to the PEM private key of the server certificate")
+	tlsClientCA := flag.String("tls-client-ca", "", "path to the PEM CA certificates that sign client certificates, clients must present one if set")
+	messageRate := flag.Float64("rate-limit", ratelimit.DefaultMessageRate, "messages per second a connection may send, 0 for no limit")
+	messageBurst := flag.Int("rate-burst", ratelimit.DefaultMessageBurst, "messages a connection may send at once above its rate")
+	ipMessageRate := flag.Float64("ip-rate-limit", ratelimit.DefaultIPMessageRate, "messages per second the connections of an IP address may send together, 0 for no limit")
+	ipMessageBurst := flag.Int("ip-rate-burst", ratelimit.DefaultIPMessageBurst, "messages the connections of an IP address may send at once above their rate")
+	maxConnectionsPerIP := flag.Int("max-connections-per-ip", ratelimit.DefaultMaxConnectionsPerIP, "connections an IP address may have open, 0 for no limit")
+	maxMessageSize := flag.Int64("max-message-size", ratelimit.DefaultMaxMessageSize, "size in bytes of the largest message a client may send, 0 for no limit")
+	maxViolations := flag.Int("max-violations", ratelimit.DefaultMaxViolations, "rate limited messages within -violation-window after which a connection is closed, 0 to never close it")
+	violationWindow := flag.Duration("violation-window", ratelimit.DefaultViolationWindow, "how long a rate limited message counts towards -max-violations, 0 counts it for the whole connection")
+	allowedOrigins := flag.String("allowed-origins", "", "comma separated origins browsers may connect from, like https://app.example.com or https://*.example.com")
+	allowAnyOrigin := flag.Bool("allow-any-origin", false, "accept websocket upgrades from any origin, only for development")
+	policyFile := flag.String("policies", "", "path to a JSON file of the policies that allow subjects to list and query pokemon, everything is allowed if empty")
+	traceExporter := flag.String("trace-exporter", tracing.NoExporter, "exporter of the query spans, none, stdout or file")
+	traceFile := flag.String("trace-file", tracing.DefaultFile, "file the spans are appended to with the file exporter")
+	logLevel := flag.String("log-level", "info", "lowest level of the logged lines, debug, info, warn or error")
+	logFormat := flag.String("log-format", logging.TextFormat, "format of the logs, text or json")
+	flag.String(config.ConfigFlag, "", "path to a JSON config file of flag values, relative paths in it are relative to its directory and environment variables and flags override it")
+	printConfig := flag.Bool(config.PrintConfigFlag, false, "print the effective settings and where they come from and exit")
+	flag.Parse()
+
+	// Fill the flags that weren't given from the environment and the config file
+	sources, err := config.Load(flag.CommandLine, "POKEMON_SERVER_", "auth-tokens", "auth-jwt-key", "tls-cert", "tls-key", "tls-client-ca", "policies", "trace-file")
+	if err != nil {
+		logging.Fatal("Error loading config", err)
+	}
+
+	if *printConfig {
+		config.Print(flag.CommandLine, sources)
+		return
+	}
+
+	// Write structured logs to stderr in the configured format
+	if err := logging.Setup(os.Stderr, *logLevel, *logFormat); err != nil {
+		logging.Fatal("Error configuring logs", err)
+	}
+
+	// Sign a token for a client instead of starting the server
+	if *issueToken != "" {
+		key, err := auth.LoadJWTKey(*authJWTKey)
+		if err != nil {
+			logging.Fatal("Error loading JWT key", err)
+		}
+
+		token, err := auth.IssueJWT(key, *issueToken, *issueTokenTTL, time.Now())
+		if err != nil {
+			logging.Fatal("Error issuing token", err)
+		}
+
+		fmt.Println(token)
+		return
+	}
+
+	// Export the spans of the queries, the spans of a query continue the
+	// trace of the client that sent it
+	shutdownTracing, err := tracing.Setup("pokemon-server", *traceExporter, *traceFile)
+	if err != nil {
+		logging.Fatal("Error configuring tracing", err)
+	}
+
+	// Flush the buffered spans when the server is stopped, there is nothing
+	// to flush without an exporter
+	if *traceExporter != tracing.NoExporter {
+		go func() {
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+			<-signals
+
+			shutdownTracing()
+			os.Exit(0)
+		}()
+	}
+
+	// Check the bearer token of each websocket upgrade, nil accepts every client
+	authenticator, err := auth.NewAuthenticator(*authTokens, *authJWTKey, time.Now)
+	if err != nil {
+		logging.Fatal("Error loading authentication config", err)
+	}
+
+	// Check the queries of each connection against the policies, nil allows all
+	var authorizer *Authorizer
+	if *policyFile != "" {
+		if authorizer, err = loadPolicies(*policyFile); err != nil {
+			logging.Fatal("Error loading policies", err)
+		}
+	}
+
+	// Check the Origin of each websocket upgrade from a browser
+	originChecker, err := origin.NewChecker(*allowedOrigins, *allowAnyOrigin)
+	if err != nil {
+		logging.Fatal("Error loading allowed origins", err)
+	}
+
+	if *allowAnyOrigin {
+		slog.Warn("Accepting websocket upgrades from any origin, do not use -allow-any-origin in production")
+	}
+
+	// Limit the connections and queries of each client and IP address
+	rateLimiter := ratelimit.New(ratelimit.Options{
+		MessageRate:         *messageRate,
+		MessageBurst:        *messageBurst,
+		IPMessageRate:       *ipMessageRate,
+		IPMessageBurst:      *ipMessageBurst,
+		MaxConnectionsPerIP: *maxConnectionsPerIP,
+		MaxMessageSize:      *maxMessageSize,
+		MaxViolations:       *maxViolations,
+		ViolationWindow:     *violationWindow,
+	}, time.Now)
+
+	// Count the connections, messages and errors for the metrics endpoint
+	metrics := newMetrics()
+	if *metricsPath != "" {
+		http.Handle(*metricsPath, metrics.handler())
+	}
+
+	// Define a WebSocket upgrade handler
+	upgrader := &websocket.Upgrader{
+		ReadBufferSize:  *readBufferSize,
+		WriteBufferSize: *writeBufferSize,
+		CheckOrigin:     originChecker.Check,
+	}
+
+	// Define an HTTP handle to handle root path requests
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "Hello, this is the Pokemon WebSocket server!")
 	})
 
-	// Start the HTTP server
-	log.Println("Starting server...")
-	err := http.ListenAndServe(":8080", nil)
+	// Handle the WebSocket connections
+	http.HandleFunc(*wsPath, webSocketHandler(upgrader, authenticator, authorizer, rateLimiter, metrics))
+
+	// Serve wss:// when a certificate is given, clients are identified by
+	// their certificate when a client CA is given as well
+	tlsConfig, err := tlsconfig.NewServer(*tlsCert, *tlsKey, *tlsClientCA)
+	if err != nil {
+		logging.Fatal("Error loading TLS config", err)
+	}
+
+	slog.Info("Starting server", "addr", *addr, "ws_path", *wsPath, "tls", tlsConfig != nil)
+	if tlsConfig != nil {
+		httpServer := &http.Server{Addr: *addr, TLSConfig: tlsConfig}
+		err = httpServer.ListenAndServeTLS("", "")
+	} else {
+		err = http.ListenAndServe(*addr, nil)
+	}
 	if err != nil {
-		log.Fatal("Error starting server:", err)
+		logging.Fatal("Error starting server", err)
 	}
 }
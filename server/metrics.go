@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	pb "server/pokemon"
+)
+
+// The reasons a query is dropped instead of answered
+const (
+	dropRateLimited = "rate_limited"
+	dropInvalid     = "invalid"
+)
+
+// Metrics are the Prometheus metrics of the server that are served on
+// /metrics, they can be updated from any goroutine
+type Metrics struct {
+	registry *prometheus.Registry
+
+	connections      prometheus.Gauge
+	messagesReceived *prometheus.CounterVec
+	messagesSent     *prometheus.CounterVec
+	queryDuration    *prometheus.HistogramVec
+	errors           *prometheus.CounterVec
+	messagesDropped  *prometheus.CounterVec
+}
+
+// Create the metrics and register them with the Go runtime and process
+// metrics in a registry of their own
+func newMetrics() *Metrics {
+	var metrics = &Metrics{
+		registry: prometheus.NewRegistry(),
+		connections: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "pokemon_server",
+			Name:      "connections",
+			Help:      "Number of open websocket connections.",
+		}),
+		messagesReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "pokemon_server",
+			Name:      "messages_received_total",
+			Help:      "Messages received from the clients by type.",
+		}, []string{"type"}),
+		messagesSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "pokemon_server",
+			Name:      "messages_sent_total",
+			Help:      "Messages sent to the clients by type.",
+		}, []string{"type"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "pokemon_server",
+			Name:      "query_duration_seconds",
+			Help:      "Time from receiving a query to handing its response to the writer by query.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, []string{"query"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "pokemon_server",
+			Name:      "errors_total",
+			Help:      "Error messages sent to the clients by error code.",
+		}, []string{"code"}),
+		messagesDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "pokemon_server",
+			Name:      "messages_dropped_total",
+			Help:      "Queries dropped instead of answered by reason.",
+		}, []string{"reason"}),
+	}
+
+	metrics.registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		metrics.connections,
+		metrics.messagesReceived,
+		metrics.messagesSent,
+		metrics.queryDuration,
+		metrics.errors,
+		metrics.messagesDropped,
+	)
+
+	return metrics
+}
+
+// Get the HTTP handler that serves the metrics in the Prometheus text format
+func (metrics *Metrics) handler() http.Handler {
+	return promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{})
+}
+
+// Get the label of a query, the field it searches by or "list" for the full
+// list and the fields joined by '_' when it searches by more than one
+func queryLabel(query *pb.PokemonQuery) string {
+	var fields []string
+	if query.Id != "" {
+		fields = append(fields, "id")
+	}
+	if query.Name != "" {
+		fields = append(fields, "name")
+	}
+	if query.Region != "" {
+		fields = append(fields, "region")
+	}
+
+	if len(fields) == 0 {
+		return listOperation
+	}
+
+	return strings.Join(fields, "_")
+}
+
+// Get the label of an error code like "invalid_request"
+func errorCodeLabel(code pb.ErrorCode) string {
+	return strings.ToLower(code.String())
+}
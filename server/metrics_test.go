@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/websocket"
+
+	pb "server/pokemon"
+	"shared/ratelimit"
+)
+
+// How long a test waits for a message or a metric before failing
+const testTimeout = 5 * time.Second
+
+// Drop the logs of the servers started by the tests
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// Serve the websocket endpoint on /ws and the metrics on /metrics of a test
+// HTTP server with the default limits and without authentication
+func startTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return startAuthorizedTestServer(t, nil)
+}
+
+// Start a test server that checks the queries against the policies of the
+// authorizer, nil allows all
+func startAuthorizedTestServer(t *testing.T, authorizer *Authorizer) *httptest.Server {
+	t.Helper()
+
+	metrics := newMetrics()
+	rateLimiter := ratelimit.New(ratelimit.DefaultOptions, time.Now)
+
+	mux := http.NewServeMux()
+	mux.Handle("/ws", webSocketHandler(&websocket.Upgrader{}, nil, authorizer, rateLimiter, metrics))
+	mux.Handle("/metrics", metrics.handler())
+
+	testServer := httptest.NewServer(mux)
+	t.Cleanup(testServer.Close)
+
+	return testServer
+}
+
+// Connect to the websocket endpoint of the test server and read the welcome
+// message
+func dialTestServer(t *testing.T, testServer *httptest.Server) *websocket.Conn {
+	t.Helper()
+
+	url := "ws" + strings.TrimPrefix(testServer.URL, "http") + "/ws"
+	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial %s: %v", url, err)
+	}
+	t.Cleanup(func() { ws.Close() })
+
+	ws.SetReadDeadline(time.Now().Add(testTimeout))
+	if messageType, _, err := ws.ReadMessage(); err != nil || messageType != websocket.TextMessage {
+		t.Fatalf("reading welcome message: type %d, %v", messageType, err)
+	}
+
+	return ws
+}
+
+// Send the bytes to the server and read its answer
+func queryTestServer(t *testing.T, ws *websocket.Conn, data []byte) *pb.WebSocketMessage {
+	t.Helper()
+
+	if err := ws.WriteMessage(websocket.BinaryMessage, data); err != nil {
+		t.Fatalf("sending query: %v", err)
+	}
+
+	ws.SetReadDeadline(time.Now().Add(testTimeout))
+	_, answer, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatalf("reading answer: %v", err)
+	}
+
+	var message = &pb.WebSocketMessage{}
+	if err := proto.Unmarshal(answer, message); err != nil {
+		t.Fatalf("unmarshaling answer: %v", err)
+	}
+
+	return message
+}
+
+// Get the value of every sample on /metrics by its name and labels as they
+// are written, like `pokemon_server_errors_total{code="invalid_request"}`
+func scrapeMetrics(t *testing.T, testServer *httptest.Server) map[string]float64 {
+	t.Helper()
+
+	response, err := http.Get(testServer.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("scraping metrics: %v", err)
+	}
+	defer response.Body.Close()
+
+	var samples = make(map[string]float64)
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		separator := strings.LastIndexByte(line, ' ')
+		value, err := strconv.ParseFloat(line[separator+1:], 64)
+		if err != nil {
+			t.Fatalf("invalid sample %q: %v", line, err)
+		}
+
+		samples[line[:separator]] = value
+	}
+
+	return samples
+}
+
+func TestMetricsScrape(t *testing.T) {
+	testServer := startTestServer(t)
+
+	first := dialTestServer(t, testServer)
+	second := dialTestServer(t, testServer)
+
+	query, err := proto.Marshal(&pb.PokemonQuery{Id: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := queryTestServer(t, first, query).GetPokemonList()
+	if list == nil || len(list.Pokemon) != 1 || list.Pokemon[0].Name != "Bulbasaur" {
+		t.Fatalf("got %v for id 1, want Bulbasaur", list)
+	}
+
+	errorMessage := queryTestServer(t, second, []byte{0xff}).GetErrorMessage()
+	if errorMessage == nil || errorMessage.ErrorCode != pb.ErrorCode_INVALID_REQUEST {
+		t.Fatalf("got %v for an invalid query, want an INVALID_REQUEST error", errorMessage)
+	}
+
+	samples := scrapeMetrics(t, testServer)
+	for sample, want := range map[string]float64{
+		`pokemon_server_connections`:                                   2,
+		`pokemon_server_messages_received_total{type="pokemon_query"}`: 1,
+		`pokemon_server_messages_sent_total{type="text"}`:              2,
+		`pokemon_server_messages_sent_total{type="pokemon_list"}`:      1,
+		`pokemon_server_messages_sent_total{type="error_message"}`:     1,
+		`pokemon_server_errors_total{code="invalid_request"}`:          1,
+		`pokemon_server_messages_dropped_total{reason="invalid"}`:      1,
+		`pokemon_server_query_duration_seconds_count{query="id"}`:      1,
+	} {
+		if got := samples[sample]; got != want {
+			t.Errorf("%s = %v, want %v", sample, got, want)
+		}
+	}
+
+	// The connection gauge goes down when a client disconnects
+	second.Close()
+
+	var connections float64
+	for deadline := time.Now().Add(testTimeout); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if connections = scrapeMetrics(t, testServer)[`pokemon_server_connections`]; connections == 1 {
+			return
+		}
+	}
+
+	t.Fatalf("pokemon_server_connections = %v after a client disconnected, want 1", connections)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+
+	pb "server/pokemon"
+	"shared/auth"
+	"shared/authz"
+)
+
+// The operations a policy can allow, list is a query without a filter that
+// returns every pokemon and query looks pokemon up by id, name or region
+const (
+	listOperation  = "list"
+	queryOperation = "query"
+)
+
+// Authorizer checks the operations of a connection against the policies,
+// anything no policy allows is denied
+type Authorizer struct {
+	policies []*authz.Policy
+}
+
+// Load and validate the policies from a JSON file
+func loadPolicies(path string) (*Authorizer, error) {
+	var policies []*authz.Policy
+	if err := authz.ReadFile(path, &policies); err != nil {
+		return nil, err
+	}
+
+	for i, policy := range policies {
+		if err := policy.Validate(listOperation, queryOperation); err != nil {
+			return nil, fmt.Errorf("policy %d: %w", i+1, err)
+		}
+	}
+
+	return &Authorizer{policies: policies}, nil
+}
+
+// Check if the identity may run the operation, a nil authorizer allows
+// everything
+func (authorizer *Authorizer) allowed(identity *auth.Identity, operation string) bool {
+	if authorizer == nil {
+		return true
+	}
+
+	for _, policy := range authorizer.policies {
+		if policy.Allows(identity.Subject, operation) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get the operation a query runs
+func queryOperationOf(query *pb.PokemonQuery) string {
+	if query.Id == "" && query.Name == "" && query.Region == "" {
+		return listOperation
+	}
+
+	return queryOperation
+}
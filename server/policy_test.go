@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	pb "server/pokemon"
+	"shared/auth"
+	"shared/authz"
+)
+
+func TestAuthorizerAllowed(t *testing.T) {
+	authorizer := &Authorizer{policies: []*authz.Policy{
+		{Subjects: []string{"*"}, Operations: []string{queryOperation}},
+		{Subjects: []string{"alice"}, Operations: []string{listOperation}},
+		{Subjects: []string{"admin"}, Operations: []string{authz.Any}},
+	}}
+
+	tests := []struct {
+		subject, operation string
+		want               bool
+	}{
+		{subject: "bob", operation: queryOperation, want: true},
+		{subject: "bob", operation: listOperation, want: false},
+		{subject: "alice", operation: listOperation, want: true},
+		{subject: "alice", operation: queryOperation, want: true},
+		{subject: "admin", operation: listOperation, want: true},
+		{subject: "anonymous", operation: listOperation, want: false},
+	}
+
+	for _, test := range tests {
+		identity := &auth.Identity{Subject: test.subject, Method: "token"}
+		if got := authorizer.allowed(identity, test.operation); got != test.want {
+			t.Errorf("%s %s = %v, want %v", test.subject, test.operation, got, test.want)
+		}
+	}
+
+	// Without policies everything is allowed, with none that match nothing is
+	var none *Authorizer
+	if !none.allowed(&auth.Identity{Subject: "anonymous", Method: "none"}, listOperation) {
+		t.Error("a nil authorizer denied a list")
+	}
+
+	if (&Authorizer{}).allowed(&auth.Identity{Subject: "alice", Method: "token"}, queryOperation) {
+		t.Error("an authorizer without policies allowed a query")
+	}
+}
+
+func TestQueryOperationOf(t *testing.T) {
+	tests := []struct {
+		query *pb.PokemonQuery
+		want  string
+	}{
+		{query: &pb.PokemonQuery{}, want: listOperation},
+		{query: &pb.PokemonQuery{Traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}, want: listOperation},
+		{query: &pb.PokemonQuery{Id: "1"}, want: queryOperation},
+		{query: &pb.PokemonQuery{Name: "Bulbasaur"}, want: queryOperation},
+		{query: &pb.PokemonQuery{Region: "Kanto"}, want: queryOperation},
+	}
+
+	for _, test := range tests {
+		if got := queryOperationOf(test.query); got != test.want {
+			t.Errorf("queryOperationOf(%v) = %s, want %s", test.query, got, test.want)
+		}
+	}
+}
+
+func TestLoadPolicies(t *testing.T) {
+	if _, err := loadPolicies("policies.example.json"); err != nil {
+		t.Fatalf("loading the example policies: %v", err)
+	}
+
+	tests := map[string]string{
+		"no operations":     `{"policies": [{"subjects": ["*"]}]}`,
+		"unknown operation": `{"policies": [{"subjects": ["*"], "operations": ["subscribe"]}]}`,
+		"invalid JSON":      `{"policies": [`,
+	}
+
+	for name, config := range tests {
+		path := filepath.Join(t.TempDir(), "policies.json")
+		if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := loadPolicies(path); err == nil {
+			t.Errorf("%s: loaded %s", name, config)
+		}
+	}
+}
+
+func TestUnauthorizedQuery(t *testing.T) {
+	testServer := startAuthorizedTestServer(t, &Authorizer{policies: []*authz.Policy{
+		{Subjects: []string{"anonymous"}, Operations: []string{queryOperation}},
+	}})
+	ws := dialTestServer(t, testServer)
+
+	list, err := proto.Marshal(&pb.PokemonQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errorMessage := queryTestServer(t, ws, list).GetErrorMessage()
+	if errorMessage == nil || errorMessage.ErrorCode != pb.ErrorCode_UNAUTHORIZED {
+		t.Fatalf("got %v for a list, want an UNAUTHORIZED error", errorMessage)
+	}
+
+	// The operations the policies allow still run on the same connection
+	query, err := proto.Marshal(&pb.PokemonQuery{Region: "Kanto"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result := queryTestServer(t, ws, query).GetPokemonList(); result == nil || len(result.Pokemon) != len(pokemonList.Pokemon) {
+		t.Fatalf("got %v for a region query, want every Kanto pokemon", result)
+	}
+}
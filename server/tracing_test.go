@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	pb "server/pokemon"
+	"shared/tracing"
+)
+
+func TestQuerySpanContinuesClientTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	// The span of the client's query, its traceparent is sent with the query
+	ctx, clientSpan := provider.Tracer("client").Start(context.Background(), "PokemonQuery", trace.WithSpanKind(trace.SpanKindClient))
+	clientSpan.End()
+
+	query, err := proto.Marshal(&pb.PokemonQuery{Id: "1", Traceparent: tracing.InjectTraceparent(ctx)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testServer := startTestServer(t)
+	ws := dialTestServer(t, testServer)
+	if list := queryTestServer(t, ws, query).GetPokemonList(); list == nil || len(list.Pokemon) != 1 {
+		t.Fatalf("got %v for id 1, want one pokemon", list)
+	}
+
+	// The span is ended after the answer is queued
+	var serverSpan sdktrace.ReadOnlySpan
+	for deadline := time.Now().Add(testTimeout); serverSpan == nil && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		for _, span := range recorder.Ended() {
+			if span.Name() == "PokemonQuery" && span.SpanKind() == trace.SpanKindServer {
+				serverSpan = span
+			}
+		}
+	}
+	if serverSpan == nil {
+		t.Fatal("the server ended no PokemonQuery span")
+	}
+
+	parent := serverSpan.Parent()
+	if !parent.IsRemote() {
+		t.Errorf("parent of the server span is not remote")
+	}
+	if parent.TraceID() != clientSpan.SpanContext().TraceID() {
+		t.Errorf("server span trace id = %s, want the client's %s", parent.TraceID(), clientSpan.SpanContext().TraceID())
+	}
+	if parent.SpanID() != clientSpan.SpanContext().SpanID() {
+		t.Errorf("server span parent id = %s, want the client span %s", parent.SpanID(), clientSpan.SpanContext().SpanID())
+	}
+}
@@ -0,0 +1,328 @@
+// Package auth authenticates websocket upgrades with bearer tokens, JWTs and
+// client certificates
+package auth
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// The shortest HMAC key accepted for signing JWTs, HS256 needs 256 bits
+const minJWTKeySize = 32
+
+// The query parameter a bearer token can be sent in when the client can't set
+// the Authorization header, like a browser opening a websocket
+const accessTokenParameter = "access_token"
+
+// The errors returned for a rejected token, they are only logged since the
+// client just gets a 401
+var (
+	errMissingToken = errors.New("missing bearer token")
+	errInvalidToken = errors.New("invalid token")
+	errExpiredToken = errors.New("token expired")
+)
+
+// Identity is who a connection authenticated as, the method is "token",
+// "jwt" or "mtls" and "none" when authentication is disabled. A server with
+// both an authenticator and a client CA requires a valid certificate and a
+// valid token: the subject of the token is the Subject that policies are
+// checked against, the subject of the certificate is kept in Certificate and
+// "+mtls" is added to the method, like "jwt+mtls"
+type Identity struct {
+	Subject     string
+	Method      string
+	Certificate string
+}
+
+// Authenticator checks a bearer token and returns the identity it belongs to
+type Authenticator interface {
+	Authenticate(token string) (*Identity, error)
+}
+
+// AuthenticatorChain accepts a token that any of its authenticators accepts
+type AuthenticatorChain []Authenticator
+
+// TokenFileAuthenticator accepts the static tokens of a token file, only the
+// hashes of the tokens are kept so a lookup doesn't depend on how much of a
+// token matches
+type TokenFileAuthenticator struct {
+	subjects map[[sha256.Size]byte]string
+}
+
+// JWTAuthenticator accepts JWTs signed with HS256 and the local key, the
+// subject of the identity is the "sub" claim
+type JWTAuthenticator struct {
+	key []byte
+	now func() time.Time
+}
+
+// JWTHeader is the header of a JWT
+type JWTHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+}
+
+// JWTClaims are the claims of a JWT that are checked, times are unix seconds
+type JWTClaims struct {
+	Subject   string   `json:"sub"`
+	IssuedAt  *float64 `json:"iat,omitempty"`
+	ExpiresAt *float64 `json:"exp,omitempty"`
+	NotBefore *float64 `json:"nbf,omitempty"`
+}
+
+// Create the authenticator for the token file and the JWT key file, either
+// may be empty and nil is returned when both are so every client is accepted
+func NewAuthenticator(tokenFile string, jwtKeyFile string, now func() time.Time) (Authenticator, error) {
+	var chain AuthenticatorChain
+
+	if tokenFile != "" {
+		authenticator, err := loadTokenFile(tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, authenticator)
+	}
+
+	if jwtKeyFile != "" {
+		key, err := LoadJWTKey(jwtKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, &JWTAuthenticator{key: key, now: now})
+	}
+
+	if len(chain) == 0 {
+		return nil, nil
+	}
+
+	return chain, nil
+}
+
+// Try the authenticators in order, the error of the last one is returned if
+// none of them accepts the token
+func (chain AuthenticatorChain) Authenticate(token string) (*Identity, error) {
+	var err = errInvalidToken
+	for _, authenticator := range chain {
+		var identity *Identity
+		if identity, err = authenticator.Authenticate(token); err == nil {
+			return identity, nil
+		}
+	}
+
+	return nil, err
+}
+
+// Load a token file, each line is a token followed by the subject it
+// authenticates, empty lines and lines starting with '#' are skipped
+func loadTokenFile(path string) (*TokenFileAuthenticator, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var authenticator = &TokenFileAuthenticator{subjects: make(map[[sha256.Size]byte]string)}
+	var scanner = bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected a token and a subject", path, number)
+		}
+
+		authenticator.subjects[sha256.Sum256([]byte(fields[0]))] = fields[1]
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return authenticator, nil
+}
+
+// Check that the token is in the token file
+func (authenticator *TokenFileAuthenticator) Authenticate(token string) (*Identity, error) {
+	subject, ok := authenticator.subjects[sha256.Sum256([]byte(token))]
+	if !ok {
+		return nil, errInvalidToken
+	}
+
+	return &Identity{Subject: subject, Method: "token"}, nil
+}
+
+// Load the HMAC key of the JWTs from a file, surrounding whitespace is ignored
+func LoadJWTKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var key = []byte(strings.TrimSpace(string(data)))
+	if len(key) < minJWTKeySize {
+		return nil, fmt.Errorf("JWT key in %s must be at least %d bytes", path, minJWTKeySize)
+	}
+
+	return key, nil
+}
+
+// Check the signature, the expiry and the subject of the JWT
+func (authenticator *JWTAuthenticator) Authenticate(token string) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errInvalidToken
+	}
+
+	var header JWTHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil || header.Algorithm != "HS256" {
+		return nil, errInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, signJWT(authenticator.key, parts[0]+"."+parts[1])) {
+		return nil, errInvalidToken
+	}
+
+	var claims JWTClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil || claims.Subject == "" {
+		return nil, errInvalidToken
+	}
+
+	now := float64(authenticator.now().Unix())
+	if claims.ExpiresAt != nil && now >= *claims.ExpiresAt {
+		return nil, errExpiredToken
+	}
+
+	if claims.NotBefore != nil && now < *claims.NotBefore {
+		return nil, errInvalidToken
+	}
+
+	return &Identity{Subject: claims.Subject, Method: "jwt"}, nil
+}
+
+// Create a JWT for the subject that expires after ttl, it is signed with the
+// key so a JWTAuthenticator with the same key accepts it
+func IssueJWT(key []byte, subject string, ttl time.Duration, now time.Time) (string, error) {
+	var issuedAt = float64(now.Unix())
+	var expiresAt = float64(now.Add(ttl).Unix())
+
+	header, err := json.Marshal(&JWTHeader{Algorithm: "HS256", Type: "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(&JWTClaims{Subject: subject, IssuedAt: &issuedAt, ExpiresAt: &expiresAt})
+	if err != nil {
+		return "", err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signJWT(key, signed)), nil
+}
+
+// Compute the HS256 signature of the encoded header and claims
+func signJWT(key []byte, signed string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signed))
+
+	return mac.Sum(nil)
+}
+
+// Decode a base64url encoded JSON part of a JWT
+func decodeJWTPart(part string, value interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, value)
+}
+
+// Get the bearer token of the request from the Authorization header or the
+// access_token query parameter
+func bearerToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+
+	return r.URL.Query().Get(accessTokenParameter)
+}
+
+// Authenticate an upgrade request, a rejected request is answered with a 401
+// and nil is returned. Without an authenticator every request is accepted as
+// the subject of its client certificate when mutual TLS is enabled, with one
+// the token is required and the certificate is recorded on its identity
+func AuthenticateUpgrade(authenticator Authenticator, w http.ResponseWriter, r *http.Request) *Identity {
+	certificate := CertificateIdentity(r)
+	if authenticator == nil {
+		if certificate != nil {
+			return certificate
+		}
+		return &Identity{Subject: "anonymous", Method: "none"}
+	}
+
+	var err = errMissingToken
+	var identity *Identity
+	if token := bearerToken(r); token != "" {
+		identity, err = authenticator.Authenticate(token)
+	}
+
+	if err != nil {
+		slog.Warn("Rejected connection", "remote_addr", r.RemoteAddr, "error", err)
+		w.Header().Set("WWW-Authenticate", `Bearer realm="ws"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+
+	if certificate != nil {
+		identity = &Identity{
+			Subject:     identity.Subject,
+			Method:      identity.Method + "+mtls",
+			Certificate: certificate.Subject,
+		}
+	}
+
+	return identity
+}
+
+// Get the identity of the verified client certificate of the request, the
+// subject is the common name of the certificate or its first DNS name or email
+// address, nil is returned when the client didn't present one
+func CertificateIdentity(r *http.Request) *Identity {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	certificate := r.TLS.VerifiedChains[0][0]
+
+	var subject = certificate.Subject.CommonName
+	if subject == "" && len(certificate.DNSNames) > 0 {
+		subject = certificate.DNSNames[0]
+	}
+	if subject == "" && len(certificate.EmailAddresses) > 0 {
+		subject = certificate.EmailAddresses[0]
+	}
+	if subject == "" {
+		return nil
+	}
+
+	return &Identity{Subject: subject, Method: "mtls", Certificate: subject}
+}
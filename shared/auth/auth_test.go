@@ -0,0 +1,291 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The key the test JWTs are signed with and the time the authenticator is at
+var (
+	testJWTKey = []byte("0123456789abcdef0123456789abcdef")
+	testNow    = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+)
+
+// Drop the logs of the rejected requests
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// Encode the header and claims as a JWT signed with HS256 and the key, the
+// algorithm in the header may say otherwise
+func newTestJWT(t *testing.T, key []byte, header map[string]interface{}, claims map[string]interface{}) string {
+	t.Helper()
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signJWT(key, signed))
+}
+
+// Get the unix seconds of the offset from testNow as a JWT time claim
+func testJWTTime(offset time.Duration) float64 {
+	return float64(testNow.Add(offset).Unix())
+}
+
+func TestJWTAuthenticate(t *testing.T) {
+	authenticator := &JWTAuthenticator{key: testJWTKey, now: func() time.Time { return testNow }}
+
+	hs256 := map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+	valid := newTestJWT(t, testJWTKey, hs256, map[string]interface{}{"sub": "alice", "exp": testJWTTime(time.Hour)})
+	parts := strings.Split(valid, ".")
+
+	// The claims of another subject with the signature of the valid token
+	forgedClaims, _ := json.Marshal(map[string]interface{}{"sub": "mallory", "exp": testJWTTime(time.Hour)})
+	tamperedPayload := parts[0] + "." + base64.RawURLEncoding.EncodeToString(forgedClaims) + "." + parts[2]
+
+	// The valid token with the first byte of its signature changed
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	signature[0] ^= 0xff
+	tamperedSignature := parts[0] + "." + parts[1] + "." + base64.RawURLEncoding.EncodeToString(signature)
+
+	// An unsigned token, the signature part is empty
+	noneHeader, _ := json.Marshal(map[string]interface{}{"alg": "none"})
+	unsigned := base64.RawURLEncoding.EncodeToString(noneHeader) + "." + parts[1] + "."
+
+	tests := []struct {
+		name        string
+		token       string
+		wantSubject string
+		wantErr     error
+	}{
+		{name: "valid", token: valid, wantSubject: "alice"},
+		{name: "alg none", token: unsigned, wantErr: errInvalidToken},
+		{name: "alg none signed", token: newTestJWT(t, testJWTKey, map[string]interface{}{"alg": "none"}, map[string]interface{}{"sub": "alice"}), wantErr: errInvalidToken},
+		{name: "alg HS512", token: newTestJWT(t, testJWTKey, map[string]interface{}{"alg": "HS512"}, map[string]interface{}{"sub": "alice"}), wantErr: errInvalidToken},
+		{name: "tampered payload", token: tamperedPayload, wantErr: errInvalidToken},
+		{name: "tampered signature", token: tamperedSignature, wantErr: errInvalidToken},
+		{name: "other key", token: newTestJWT(t, []byte("fedcba9876543210fedcba9876543210"), hs256, map[string]interface{}{"sub": "alice"}), wantErr: errInvalidToken},
+		{name: "two parts", token: parts[0] + "." + parts[1], wantErr: errInvalidToken},
+		{name: "expires now", token: newTestJWT(t, testJWTKey, hs256, map[string]interface{}{"sub": "alice", "exp": testJWTTime(0)}), wantErr: errExpiredToken},
+		{name: "expired", token: newTestJWT(t, testJWTKey, hs256, map[string]interface{}{"sub": "alice", "exp": testJWTTime(-time.Second)}), wantErr: errExpiredToken},
+		{name: "expires in a second", token: newTestJWT(t, testJWTKey, hs256, map[string]interface{}{"sub": "alice", "exp": testJWTTime(time.Second)}), wantSubject: "alice"},
+		{name: "not before now", token: newTestJWT(t, testJWTKey, hs256, map[string]interface{}{"sub": "alice", "nbf": testJWTTime(0)}), wantSubject: "alice"},
+		{name: "not before in the future", token: newTestJWT(t, testJWTKey, hs256, map[string]interface{}{"sub": "alice", "nbf": testJWTTime(time.Second)}), wantErr: errInvalidToken},
+		{name: "empty subject", token: newTestJWT(t, testJWTKey, hs256, map[string]interface{}{"sub": "", "exp": testJWTTime(time.Hour)}), wantErr: errInvalidToken},
+		{name: "no subject", token: newTestJWT(t, testJWTKey, hs256, map[string]interface{}{"exp": testJWTTime(time.Hour)}), wantErr: errInvalidToken},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			identity, err := authenticator.Authenticate(test.token)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+
+			if test.wantErr != nil {
+				return
+			}
+
+			if identity.Subject != test.wantSubject || identity.Method != "jwt" {
+				t.Errorf("got %+v, want the jwt identity of %s", identity, test.wantSubject)
+			}
+		})
+	}
+}
+
+func TestIssueJWT(t *testing.T) {
+	token, err := IssueJWT(testJWTKey, "alice", time.Hour, testNow)
+	if err != nil {
+		t.Fatalf("IssueJWT: %v", err)
+	}
+
+	var now = testNow
+	authenticator := &JWTAuthenticator{key: testJWTKey, now: func() time.Time { return now }}
+
+	if identity, err := authenticator.Authenticate(token); err != nil || identity.Subject != "alice" {
+		t.Fatalf("got %+v, %v for an issued token, want alice", identity, err)
+	}
+
+	now = testNow.Add(time.Hour)
+	if _, err := authenticator.Authenticate(token); !errors.Is(err, errExpiredToken) {
+		t.Fatalf("got %v once the ttl passed, want %v", err, errExpiredToken)
+	}
+}
+
+func TestTokenFileAuthenticate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens")
+	if err := os.WriteFile(path, []byte("# tokens\n\nsecret-a alice\nsecret-b bob\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	authenticator, err := loadTokenFile(path)
+	if err != nil {
+		t.Fatalf("loadTokenFile: %v", err)
+	}
+
+	for token, want := range map[string]string{"secret-a": "alice", "secret-b": "bob"} {
+		if identity, err := authenticator.Authenticate(token); err != nil || identity.Subject != want || identity.Method != "token" {
+			t.Errorf("got %+v, %v for %s, want the token identity of %s", identity, err, token, want)
+		}
+	}
+
+	for _, token := range []string{"secret", "secret-a ", "alice", "# tokens"} {
+		if _, err := authenticator.Authenticate(token); !errors.Is(err, errInvalidToken) {
+			t.Errorf("got %v for %q, want %v", err, token, errInvalidToken)
+		}
+	}
+
+	// A line without a subject is an error
+	if err := os.WriteFile(path, []byte("secret-a\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadTokenFile(path); err == nil {
+		t.Error("loaded a token without a subject")
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		url           string
+		want          string
+	}{
+		{name: "bearer header", authorization: "Bearer abc", url: "/ws", want: "abc"},
+		{name: "lower case scheme", authorization: "bearer abc", url: "/ws", want: "abc"},
+		{name: "query parameter", url: "/ws?access_token=abc", want: "abc"},
+		{name: "header over query parameter", authorization: "Bearer abc", url: "/ws?access_token=def", want: "abc"},
+		{name: "basic header", authorization: "Basic YWxpY2U6c2VjcmV0", url: "/ws?access_token=abc", want: ""},
+		{name: "scheme only", authorization: "Bearer", url: "/ws?access_token=abc", want: ""},
+		{name: "nothing", url: "/ws", want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, test.url, nil)
+			if test.authorization != "" {
+				r.Header.Set("Authorization", test.authorization)
+			}
+
+			if got := bearerToken(r); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+// Add a verified client certificate with the common name to the request
+func withTestCertificate(r *http.Request, commonName string) *http.Request {
+	r.TLS = &tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: commonName}}}},
+	}
+
+	return r
+}
+
+func TestAuthenticateUpgrade(t *testing.T) {
+	authenticator := &JWTAuthenticator{key: testJWTKey, now: func() time.Time { return testNow }}
+	token, err := IssueJWT(testJWTKey, "alice", time.Hour, testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		authenticator Authenticator
+		request       *http.Request
+		want          *Identity
+	}{
+		{
+			name:    "no authenticator",
+			request: httptest.NewRequest(http.MethodGet, "/ws", nil),
+			want:    &Identity{Subject: "anonymous", Method: "none"},
+		},
+		{
+			name:    "no authenticator with a certificate",
+			request: withTestCertificate(httptest.NewRequest(http.MethodGet, "/ws", nil), "client"),
+			want:    &Identity{Subject: "client", Method: "mtls", Certificate: "client"},
+		},
+		{
+			name:          "valid token",
+			authenticator: authenticator,
+			request:       httptest.NewRequest(http.MethodGet, "/ws?access_token="+token, nil),
+			want:          &Identity{Subject: "alice", Method: "jwt"},
+		},
+		{
+			name:          "valid token with a certificate",
+			authenticator: authenticator,
+			request:       withTestCertificate(httptest.NewRequest(http.MethodGet, "/ws?access_token="+token, nil), "client"),
+			want:          &Identity{Subject: "alice", Method: "jwt+mtls", Certificate: "client"},
+		},
+		{
+			name:          "missing token",
+			authenticator: authenticator,
+			request:       httptest.NewRequest(http.MethodGet, "/ws", nil),
+		},
+		{
+			name:          "missing token with a certificate",
+			authenticator: authenticator,
+			request:       withTestCertificate(httptest.NewRequest(http.MethodGet, "/ws", nil), "client"),
+		},
+		{
+			name:          "invalid token",
+			authenticator: authenticator,
+			request:       httptest.NewRequest(http.MethodGet, "/ws?access_token=abc", nil),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			identity := AuthenticateUpgrade(test.authenticator, recorder, test.request)
+
+			if test.want == nil {
+				if identity != nil {
+					t.Fatalf("got %+v, want the request rejected", identity)
+				}
+
+				if recorder.Code != http.StatusUnauthorized {
+					t.Errorf("got status %d, want %d", recorder.Code, http.StatusUnauthorized)
+				}
+
+				if got := recorder.Header().Get("WWW-Authenticate"); got != `Bearer realm="ws"` {
+					t.Errorf("got WWW-Authenticate %q, want a Bearer challenge", got)
+				}
+				return
+			}
+
+			if identity == nil || *identity != *test.want {
+				t.Fatalf("got %+v, want %+v", identity, test.want)
+			}
+
+			// An accepted request is left for the upgrade to answer
+			if recorder.Code != http.StatusOK || recorder.Body.Len() != 0 {
+				t.Errorf("got status %d and %q, want nothing written", recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Package authz reads the JSON policies that allow subjects to run the
+// operations of a server and matches them
+package authz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// The subject or operation of a policy that matches any
+const Any = "*"
+
+// Policy allows the subjects to run the operations, a server embeds it in
+// its own policy type when it checks more than the operation
+type Policy struct {
+	Subjects   []string `json:"subjects"`
+	Operations []string `json:"operations"`
+}
+
+// Read the "policies" array of a JSON policy file into the slice policies
+// points to
+func ReadFile(path string, policies any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var config struct {
+		Policies json.RawMessage `json:"policies"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return err
+	}
+
+	if len(config.Policies) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(config.Policies, policies)
+}
+
+// Check that the policy has subjects and that its operations are among the
+// operations of the server or the wildcard
+func (policy *Policy) Validate(operations ...string) error {
+	if len(policy.Subjects) == 0 || len(policy.Operations) == 0 {
+		return errors.New("needs subjects and operations")
+	}
+
+	for _, operation := range policy.Operations {
+		if operation != Any && !contains(operations, operation) {
+			return fmt.Errorf("unknown operation %q", operation)
+		}
+	}
+
+	return nil
+}
+
+// Check if the policy allows the subject to run the operation
+func (policy *Policy) Allows(subject string, operation string) bool {
+	return containsOrAny(policy.Subjects, subject) && containsOrAny(policy.Operations, operation)
+}
+
+// Check if the values contain the value
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check if the values of a policy contain the value or the wildcard
+func containsOrAny(values []string, value string) bool {
+	return contains(values, value) || contains(values, Any)
+}
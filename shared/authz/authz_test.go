@@ -0,0 +1,88 @@
+package authz
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPolicyAllows(t *testing.T) {
+	tests := []struct {
+		policy             Policy
+		subject, operation string
+		want               bool
+	}{
+		{policy: Policy{Subjects: []string{"alice"}, Operations: []string{"query"}}, subject: "alice", operation: "query", want: true},
+		{policy: Policy{Subjects: []string{"alice"}, Operations: []string{"query"}}, subject: "bob", operation: "query", want: false},
+		{policy: Policy{Subjects: []string{"alice"}, Operations: []string{"query"}}, subject: "alice", operation: "list", want: false},
+		{policy: Policy{Subjects: []string{Any}, Operations: []string{"query"}}, subject: "bob", operation: "query", want: true},
+		{policy: Policy{Subjects: []string{"admin"}, Operations: []string{Any}}, subject: "admin", operation: "publish", want: true},
+		{policy: Policy{Subjects: []string{"alice", "bob"}, Operations: []string{"list", "query"}}, subject: "bob", operation: "list", want: true},
+		{policy: Policy{Subjects: []string{"Alice"}, Operations: []string{"query"}}, subject: "alice", operation: "query", want: false},
+	}
+
+	for _, test := range tests {
+		if got := test.policy.Allows(test.subject, test.operation); got != test.want {
+			t.Errorf("%v allows %s %s = %v, want %v", test.policy, test.subject, test.operation, got, test.want)
+		}
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	operations := []string{"list", "query"}
+
+	tests := []struct {
+		policy Policy
+		valid  bool
+	}{
+		{policy: Policy{Subjects: []string{Any}, Operations: []string{"list", "query"}}, valid: true},
+		{policy: Policy{Subjects: []string{"alice"}, Operations: []string{Any}}, valid: true},
+		{policy: Policy{Subjects: []string{"alice"}}, valid: false},
+		{policy: Policy{Operations: []string{"list"}}, valid: false},
+		{policy: Policy{Subjects: []string{"alice"}, Operations: []string{"publish"}}, valid: false},
+	}
+
+	for _, test := range tests {
+		if err := test.policy.Validate(operations...); (err == nil) != test.valid {
+			t.Errorf("Validate(%v) = %v, want valid %v", test.policy, err, test.valid)
+		}
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	tests := map[string]struct {
+		config string
+		want   int
+		valid  bool
+	}{
+		"policies":    {config: `{"policies": [{"subjects": ["*"], "operations": ["list"], "channels": ["news"]}]}`, want: 1, valid: true},
+		"no policies": {config: `{}`, want: 0, valid: true},
+		"not a list":  {config: `{"policies": {"subjects": ["*"]}}`, valid: false},
+		"invalid":     {config: `{"policies": [`, valid: false},
+	}
+
+	for name, test := range tests {
+		path := filepath.Join(t.TempDir(), "policies.json")
+		if err := os.WriteFile(path, []byte(test.config), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		// A server reads the fields of its own policy type next to the shared ones
+		var policies []*struct {
+			Policy
+			Channels []string `json:"channels"`
+		}
+		err := ReadFile(path, &policies)
+		if (err == nil) != test.valid || len(policies) != test.want {
+			t.Errorf("%s: read %d policies, %v, want %d valid %v", name, len(policies), err, test.want, test.valid)
+		}
+
+		if len(policies) > 0 && (policies[0].Subjects[0] != Any || policies[0].Channels[0] != "news") {
+			t.Errorf("%s: read %+v", name, policies[0])
+		}
+	}
+
+	if err := ReadFile(filepath.Join(t.TempDir(), "missing.json"), &[]*Policy{}); err == nil {
+		t.Error("read a missing file")
+	}
+}
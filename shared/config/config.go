@@ -0,0 +1,163 @@
+// Package config loads the settings of the binaries from their flags, the
+// environment and a JSON config file
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The flag with the path of the config file and the flag that prints the
+// effective settings, they can't be set in the config file
+const (
+	ConfigFlag      = "config"
+	PrintConfigFlag = "print-config"
+)
+
+// The places a setting can come from, from the highest to the lowest precedence
+const (
+	flagSource        = "flag"
+	environmentSource = "env"
+	configFileSource  = "config"
+	defaultSource     = "default"
+)
+
+// Apply the environment variables and the config file to the flags that
+// weren't set on the command line and return where each flag got its value.
+// The variable of a flag is the prefix followed by its name in upper case
+// with '-' replaced by '_', the config file is a JSON object of flag names
+// and values and its path is the config flag or its variable. The relative
+// values of the path flags are relative to the directory of the config file
+// when they come from it and to the working directory otherwise
+func Load(flags *flag.FlagSet, envPrefix string, paths ...string) (map[string]string, error) {
+	var sources = make(map[string]string)
+	flags.VisitAll(func(f *flag.Flag) {
+		sources[f.Name] = defaultSource
+	})
+	flags.Visit(func(f *flag.Flag) {
+		sources[f.Name] = flagSource
+	})
+
+	// The config file can only be named by a flag or a variable
+	if sources[ConfigFlag] == defaultSource {
+		if value, ok := os.LookupEnv(environmentVariable(envPrefix, ConfigFlag)); ok {
+			if err := flags.Set(ConfigFlag, value); err != nil {
+				return nil, err
+			}
+			sources[ConfigFlag] = environmentSource
+		}
+	}
+
+	configPath := flags.Lookup(ConfigFlag).Value.String()
+	config, err := readConfigFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range paths {
+		if value, ok := config[name]; ok && value != "" && !filepath.IsAbs(value) {
+			config[name] = filepath.Join(filepath.Dir(configPath), value)
+		}
+	}
+
+	for name := range config {
+		if flags.Lookup(name) == nil || name == ConfigFlag || name == PrintConfigFlag {
+			return nil, fmt.Errorf("unknown setting %q in config file", name)
+		}
+	}
+
+	var setErr error
+	flags.VisitAll(func(f *flag.Flag) {
+		if setErr != nil || sources[f.Name] != defaultSource || f.Name == ConfigFlag {
+			return
+		}
+
+		if value, ok := os.LookupEnv(environmentVariable(envPrefix, f.Name)); ok {
+			if err := flags.Set(f.Name, value); err != nil {
+				setErr = fmt.Errorf("invalid value %q for %s: %w", value, environmentVariable(envPrefix, f.Name), err)
+				return
+			}
+			sources[f.Name] = environmentSource
+			return
+		}
+
+		if value, ok := config[f.Name]; ok {
+			if err := flags.Set(f.Name, value); err != nil {
+				setErr = fmt.Errorf("invalid value %q for %s in config file: %w", value, f.Name, err)
+				return
+			}
+			sources[f.Name] = configFileSource
+		}
+	})
+
+	return sources, setErr
+}
+
+// Get the environment variable of a flag
+func environmentVariable(envPrefix string, name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Read the flag values of a JSON config file as strings, numbers and booleans
+// are kept as they are written, an empty path is an empty config
+func readConfigFile(path string) (map[string]string, error) {
+	var config = make(map[string]string)
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	for name, value := range values {
+		switch value := value.(type) {
+		case string:
+			config[name] = value
+		case json.Number, bool:
+			config[name] = fmt.Sprint(value)
+		default:
+			return nil, fmt.Errorf("setting %q in config file must be a string, a number or a boolean", name)
+		}
+	}
+
+	return config, nil
+}
+
+// Print the effective value of every flag and where it comes from, the
+// values of the secret flags are masked
+func Print(flags *flag.FlagSet, sources map[string]string, secrets ...string) {
+	fprint(os.Stdout, flags, sources, secrets...)
+}
+
+// Write the effective value of every flag and where it comes from to w
+func fprint(w io.Writer, flags *flag.FlagSet, sources map[string]string, secrets ...string) {
+	flags.VisitAll(func(f *flag.Flag) {
+		if f.Name == PrintConfigFlag {
+			return
+		}
+
+		value := f.Value.String()
+		for _, secret := range secrets {
+			if f.Name == secret && value != "" {
+				value = "********"
+			}
+		}
+
+		fmt.Fprintf(w, "%s = %q (%s)\n", f.Name, value, sources[f.Name])
+	})
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testFlags are the flags of a binary under test
+type testFlags struct {
+	set      *flag.FlagSet
+	addr     *string
+	rate     *int
+	debug    *bool
+	channels *string
+	token    *string
+}
+
+// Declare the flags of a test binary and parse the command line arguments
+func parseTestFlags(t *testing.T, arguments ...string) *testFlags {
+	t.Helper()
+
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := &testFlags{
+		set:      set,
+		addr:     set.String("addr", ":8080", "address"),
+		rate:     set.Int("rate-limit", 20, "rate"),
+		debug:    set.Bool("debug", false, "debug"),
+		channels: set.String("channels", "", "channels file"),
+		token:    set.String("token", "", "token"),
+	}
+	set.String(ConfigFlag, "", "config file")
+	set.Bool(PrintConfigFlag, false, "print config")
+
+	if err := set.Parse(arguments); err != nil {
+		t.Fatal(err)
+	}
+
+	return flags
+}
+
+// Write the config file in a temporary directory and get its path
+func writeConfigFile(t *testing.T, config string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `{"addr": ":9000", "rate-limit": 50, "debug": true, "token": "from-file"}`)
+	t.Setenv("TEST_RATE_LIMIT", "30")
+	t.Setenv("TEST_TOKEN", "from-env")
+
+	flags := parseTestFlags(t, "-config", path, "-token", "from-flag")
+	sources, err := Load(flags.set, "TEST_")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if *flags.token != "from-flag" || sources["token"] != flagSource {
+		t.Errorf("token = %q from %s, want the flag", *flags.token, sources["token"])
+	}
+	if *flags.rate != 30 || sources["rate-limit"] != environmentSource {
+		t.Errorf("rate-limit = %d from %s, want the environment", *flags.rate, sources["rate-limit"])
+	}
+	if *flags.addr != ":9000" || sources["addr"] != configFileSource {
+		t.Errorf("addr = %q from %s, want the config file", *flags.addr, sources["addr"])
+	}
+	if !*flags.debug || sources["debug"] != configFileSource {
+		t.Errorf("debug = %v from %s, want the config file", *flags.debug, sources["debug"])
+	}
+	if *flags.channels != "" || sources["channels"] != defaultSource {
+		t.Errorf("channels = %q from %s, want the default", *flags.channels, sources["channels"])
+	}
+	if sources[ConfigFlag] != flagSource {
+		t.Errorf("config from %s, want the flag", sources[ConfigFlag])
+	}
+}
+
+func TestLoadConfigFromEnvironment(t *testing.T) {
+	t.Setenv("TEST_CONFIG", writeConfigFile(t, `{"addr": ":9000"}`))
+
+	flags := parseTestFlags(t)
+	sources, err := Load(flags.set, "TEST_")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if *flags.addr != ":9000" || sources[ConfigFlag] != environmentSource {
+		t.Errorf("addr = %q with the config from %s, want the config file named by the environment", *flags.addr, sources[ConfigFlag])
+	}
+}
+
+func TestLoadRelativePaths(t *testing.T) {
+	path := writeConfigFile(t, `{"channels": "channels.example.json"}`)
+
+	flags := parseTestFlags(t, "-config", path)
+	if _, err := Load(flags.set, "TEST_", "channels"); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	// A path in the config file is relative to its directory
+	if want := filepath.Join(filepath.Dir(path), "channels.example.json"); *flags.channels != want {
+		t.Errorf("channels = %q, want %q", *flags.channels, want)
+	}
+
+	// Absolute paths and the paths of the environment are kept as they are
+	absolute := filepath.Join(t.TempDir(), "channels.json")
+	path = writeConfigFile(t, `{"channels": "`+filepath.ToSlash(absolute)+`"}`)
+	flags = parseTestFlags(t, "-config", path)
+	if _, err := Load(flags.set, "TEST_", "channels"); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if *flags.channels != filepath.ToSlash(absolute) {
+		t.Errorf("channels = %q, want %q", *flags.channels, absolute)
+	}
+
+	t.Setenv("TEST_CHANNELS", "channels.example.json")
+	flags = parseTestFlags(t, "-config", path)
+	if _, err := Load(flags.set, "TEST_", "channels"); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if *flags.channels != "channels.example.json" {
+		t.Errorf("channels = %q from the environment, want it unchanged", *flags.channels)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown setting":       `{"unknown": 1}`,
+		"config in config file": `{"config": "other.json"}`,
+		"nested value":          `{"addr": {"host": "localhost"}}`,
+		"invalid value":         `{"rate-limit": "fast"}`,
+		"invalid JSON":          `{"addr": `,
+	}
+
+	for name, config := range tests {
+		flags := parseTestFlags(t, "-config", writeConfigFile(t, config))
+		if _, err := Load(flags.set, "TEST_"); err == nil {
+			t.Errorf("%s: loaded %s", name, config)
+		}
+	}
+
+	t.Setenv("TEST_RATE_LIMIT", "fast")
+	if _, err := Load(parseTestFlags(t).set, "TEST_"); err == nil {
+		t.Error("loaded an invalid environment variable")
+	}
+}
+
+func TestPrintMasksSecrets(t *testing.T) {
+	flags := parseTestFlags(t, "-token", "secret-token", "-addr", ":9000")
+	sources, err := Load(flags.set, "TEST_")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	var output bytes.Buffer
+	fprint(&output, flags.set, sources, "token", "channels")
+
+	printed := output.String()
+	for _, line := range []string{
+		`token = "********" (flag)`,
+		`addr = ":9000" (flag)`,
+		`rate-limit = "20" (default)`,
+		`channels = "" (default)`,
+	} {
+		if !strings.Contains(printed, line+"\n") {
+			t.Errorf("missing %q in\n%s", line, printed)
+		}
+	}
+
+	if strings.Contains(printed, "secret-token") {
+		t.Errorf("the secret is printed in\n%s", printed)
+	}
+	if strings.Contains(printed, PrintConfigFlag) {
+		t.Errorf("the %s flag is printed in\n%s", PrintConfigFlag, printed)
+	}
+}
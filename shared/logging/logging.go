@@ -0,0 +1,57 @@
+// Package logging sets up the structured logs of the binaries
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// The formats the logs can be written in, text is key=value pairs and json is
+// one object per line
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+)
+
+// The id of the last connection, each connection tags its lines with its own
+var lastConnectionID atomic.Int64
+
+// Make the default logger write the records of the level and above to the
+// writer in the format, the level is debug, info, warn or error
+func Setup(w io.Writer, level string, format string) error {
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+
+	var options = &slog.HandlerOptions{Level: logLevel}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case TextFormat:
+		handler = slog.NewTextHandler(w, options)
+	case JSONFormat:
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return fmt.Errorf("invalid log format %q, expected %s or %s", format, TextFormat, JSONFormat)
+	}
+
+	slog.SetDefault(slog.New(handler))
+
+	return nil
+}
+
+// Create the logger of a new connection, its lines are tagged with a new
+// connection id, the remote address and the subject it authenticated as
+func ConnectionLogger(remoteAddr string, subject string) *slog.Logger {
+	return slog.With("conn_id", lastConnectionID.Add(1), "remote_addr", remoteAddr, "user", subject)
+}
+
+// Log the error and exit, for the errors that keep the server from starting
+func Fatal(message string, err error) {
+	slog.Error(message, "error", err)
+	os.Exit(1)
+}
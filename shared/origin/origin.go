@@ -0,0 +1,106 @@
+// Package origin checks the Origin header of websocket upgrades
+package origin
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Checker allows the websocket upgrades of browsers on the configured
+// origins, an origin is a scheme and a host like "https://app.example.com"
+// and the host may start with "*." to allow every subdomain of a domain, a
+// default port like ":443" matches the origin without one. Requests without
+// an Origin header don't come from a browser and requests from the server's
+// own origin are always allowed
+type Checker struct {
+	origins  []*url.URL
+	allowAll bool
+}
+
+// Create an origin checker for a comma separated list of origins, allowAll
+// accepts every origin and is only meant for development
+func NewChecker(origins string, allowAll bool) (*Checker, error) {
+	var checker = &Checker{allowAll: allowAll}
+	for _, origin := range strings.Split(origins, ",") {
+		origin = strings.TrimSpace(origin)
+		if origin == "" {
+			continue
+		}
+
+		parsed, err := url.Parse(origin)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" || (parsed.Path != "" && parsed.Path != "/") {
+			return nil, fmt.Errorf("invalid origin %q, expected a scheme and a host like https://app.example.com", origin)
+		}
+
+		checker.origins = append(checker.origins, parsed)
+	}
+
+	return checker, nil
+}
+
+// Check the Origin header of an upgrade request, a rejected origin is logged
+func (checker *Checker) Check(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || checker.allowAll {
+		return true
+	}
+
+	parsed, err := url.Parse(origin)
+	if err == nil && checker.allowed(parsed, r.Host) {
+		return true
+	}
+
+	slog.Warn("Rejected websocket upgrade", "remote_addr", r.RemoteAddr, "origin", origin)
+	return false
+}
+
+// Check if the origin is the server's host or one of the allowed origins
+func (checker *Checker) allowed(origin *url.URL, host string) bool {
+	if sameHost(origin, host) {
+		return true
+	}
+
+	for _, allowed := range checker.origins {
+		if !strings.EqualFold(allowed.Scheme, origin.Scheme) || port(allowed) != port(origin) {
+			continue
+		}
+
+		allowedHost, originHost := strings.ToLower(allowed.Hostname()), strings.ToLower(origin.Hostname())
+		if strings.HasPrefix(allowedHost, "*.") {
+			if strings.HasSuffix(originHost, allowedHost[1:]) {
+				return true
+			}
+		} else if allowedHost == originHost {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check if the origin is the host of the request, a host without a port is on
+// the default port of the origin's scheme
+func sameHost(origin *url.URL, host string) bool {
+	requestHost := &url.URL{Scheme: origin.Scheme, Host: host}
+
+	return strings.EqualFold(origin.Hostname(), requestHost.Hostname()) && port(origin) == port(requestHost)
+}
+
+// Get the port of the url, the default port of its scheme if it has none
+func port(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "ws":
+		return "80"
+	case "https", "wss":
+		return "443"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,88 @@
+package origin
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// Drop the logs of the rejected upgrades
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+func TestCheck(t *testing.T) {
+	checker, err := NewChecker("https://app.example.com, https://*.example.com, http://localhost:3000", false)
+	if err != nil {
+		t.Fatalf("NewChecker: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		origin string
+		host   string
+		want   bool
+	}{
+		{name: "no origin", origin: "", want: true},
+		{name: "allowed origin", origin: "https://app.example.com", want: true},
+		{name: "case insensitive", origin: "HTTPS://App.Example.com", want: true},
+		{name: "subdomain", origin: "https://api.example.com", want: true},
+		{name: "nested subdomain", origin: "https://a.b.example.com", want: true},
+		{name: "wildcard without subdomain", origin: "https://example.com", want: false},
+		{name: "suffix without a dot", origin: "https://evilexample.com", want: false},
+		{name: "domain with the allowed one as prefix", origin: "https://app.example.com.evil.com", want: false},
+		{name: "scheme mismatch", origin: "http://app.example.com", want: false},
+		{name: "explicit default port", origin: "https://app.example.com:443", want: true},
+		{name: "explicit default port of a wildcard", origin: "https://api.example.com:443", want: true},
+		{name: "other port", origin: "https://app.example.com:8443", want: false},
+		{name: "allowed port", origin: "http://localhost:3000", want: true},
+		{name: "missing allowed port", origin: "http://localhost", want: false},
+		{name: "same host", origin: "http://server.test:8080", host: "server.test:8080", want: true},
+		{name: "same host on the default port", origin: "https://server.test:443", host: "server.test", want: true},
+		{name: "same host on another port", origin: "http://server.test:9090", host: "server.test:8080", want: false},
+		{name: "other host", origin: "https://evil.test", host: "server.test", want: false},
+		{name: "null origin", origin: "null", want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+			r.Host = "server.test:8080"
+			if test.host != "" {
+				r.Host = test.host
+			}
+			if test.origin != "" {
+				r.Header.Set("Origin", test.origin)
+			}
+
+			if got := checker.Check(r); got != test.want {
+				t.Errorf("Check(%q) on %s = %v, want %v", test.origin, r.Host, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckAllowAll(t *testing.T) {
+	checker, err := NewChecker("", true)
+	if err != nil {
+		t.Fatalf("NewChecker: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	r.Header.Set("Origin", "https://evil.test")
+	if !checker.Check(r) {
+		t.Error("an allow all checker rejected an origin")
+	}
+}
+
+func TestNewCheckerInvalid(t *testing.T) {
+	for _, origins := range []string{"app.example.com", "https://", "https://app.example.com/path", "://app"} {
+		if _, err := NewChecker(origins, false); err == nil {
+			t.Errorf("NewChecker(%q) accepted an invalid origin", origins)
+		}
+	}
+}
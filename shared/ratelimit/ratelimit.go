@@ -0,0 +1,245 @@
+// Package ratelimit limits the connections and the messages of each client
+// and IP address
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// The default limits of the messages a client can send
+const (
+	DefaultMessageRate         = 20
+	DefaultMessageBurst        = 40
+	DefaultIPMessageRate       = 100
+	DefaultIPMessageBurst      = 200
+	DefaultMaxConnectionsPerIP = 20
+	DefaultMaxMessageSize      = 64 << 10
+	DefaultMaxViolations       = 10
+	DefaultViolationWindow     = time.Minute
+)
+
+// The limits of a server that isn't configured otherwise
+var DefaultOptions = Options{
+	MessageRate:         DefaultMessageRate,
+	MessageBurst:        DefaultMessageBurst,
+	IPMessageRate:       DefaultIPMessageRate,
+	IPMessageBurst:      DefaultIPMessageBurst,
+	MaxConnectionsPerIP: DefaultMaxConnectionsPerIP,
+	MaxMessageSize:      DefaultMaxMessageSize,
+	MaxViolations:       DefaultMaxViolations,
+	ViolationWindow:     DefaultViolationWindow,
+}
+
+// Options are the limits of the connections, the rates are messages
+// per second and a zero rate or maximum disables that limit. A connection is
+// closed after MaxViolations rate limited messages within ViolationWindow, a
+// zero window counts them for the whole connection
+type Options struct {
+	MessageRate         float64
+	MessageBurst        int
+	IPMessageRate       float64
+	IPMessageBurst      int
+	MaxConnectionsPerIP int
+	MaxMessageSize      int64
+	MaxViolations       int
+	ViolationWindow     time.Duration
+}
+
+// tokenBucket allows bursts of up to burst messages and refills at rate
+// messages per second
+type tokenBucket struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// Limiter keeps the message bucket and the number of connections of each
+// IP address, an IP address is forgotten when its last connection is closed
+type Limiter struct {
+	mutex   sync.Mutex
+	options Options
+	ips     map[string]*ipLimits
+	now     func() time.Time
+}
+
+// ipLimits are the limits shared by the connections of an IP address
+type ipLimits struct {
+	bucket      *tokenBucket
+	connections int
+}
+
+// Connection limits the messages of one connection, it is only used by
+// the goroutine reading the connection. Violations are the times of its rate
+// limited messages that still count towards closing it
+type Connection struct {
+	limiter    *Limiter
+	ip         string
+	bucket     *tokenBucket
+	shared     *tokenBucket
+	violations []time.Time
+}
+
+// Create a full token bucket, a zero rate allows every message
+func newTokenBucket(rate float64, burst int, now func() time.Time) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now(),
+		now:    now,
+	}
+}
+
+// Add the tokens the bucket earned since it was last refilled, the mutex of
+// the bucket must be held
+func (bucket *tokenBucket) refill() {
+	now := bucket.now()
+	bucket.tokens += now.Sub(bucket.last).Seconds() * bucket.rate
+	if bucket.tokens > bucket.burst {
+		bucket.tokens = bucket.burst
+	}
+	bucket.last = now
+}
+
+// Check if the bucket has a token left, the mutex of the bucket must be held
+func (bucket *tokenBucket) available() bool {
+	return bucket.rate <= 0 || bucket.tokens >= 1
+}
+
+// Take a token from both buckets if both have one left, nothing is taken
+// from either when one of them is empty. The first bucket is always locked
+// first, a connection passes its own bucket before the shared one
+func allowBoth(first *tokenBucket, second *tokenBucket) bool {
+	first.mutex.Lock()
+	defer first.mutex.Unlock()
+	second.mutex.Lock()
+	defer second.mutex.Unlock()
+
+	first.refill()
+	second.refill()
+	if !first.available() || !second.available() {
+		return false
+	}
+
+	if first.rate > 0 {
+		first.tokens--
+	}
+	if second.rate > 0 {
+		second.tokens--
+	}
+
+	return true
+}
+
+// Create a rate limiter with the options that tells the time with now
+func New(options Options, now func() time.Time) *Limiter {
+	return &Limiter{
+		options: options,
+		ips:     make(map[string]*ipLimits),
+		now:     now,
+	}
+}
+
+// Get the options the limiter was created with
+func (limiter *Limiter) Options() Options {
+	return limiter.options
+}
+
+// Get the IP address of the request without its port
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// Count a new connection from the IP address, nil is returned when the IP
+// address already has the maximum number of connections
+func (limiter *Limiter) Connect(ip string) *Connection {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	limits, ok := limiter.ips[ip]
+	if !ok {
+		limits = &ipLimits{bucket: newTokenBucket(limiter.options.IPMessageRate, limiter.options.IPMessageBurst, limiter.now)}
+		limiter.ips[ip] = limits
+	}
+
+	if limiter.options.MaxConnectionsPerIP > 0 && limits.connections >= limiter.options.MaxConnectionsPerIP {
+		return nil
+	}
+
+	limits.connections++
+
+	return &Connection{
+		limiter: limiter,
+		ip:      ip,
+		bucket:  newTokenBucket(limiter.options.MessageRate, limiter.options.MessageBurst, limiter.now),
+		shared:  limits.bucket,
+	}
+}
+
+// Check the limits of the connection and its IP address for a new message,
+// a message over either limit uses up neither of them. The second return value
+// is true when the connection has been over its limits too often within the
+// violation window and should be closed
+func (connection *Connection) Allow() (bool, bool) {
+	if allowBoth(connection.bucket, connection.shared) {
+		return true, false
+	}
+
+	options := connection.limiter.options
+	if options.MaxViolations <= 0 {
+		return false, false
+	}
+
+	// Forget the violations that are older than the window
+	now := connection.limiter.now()
+	if options.ViolationWindow > 0 {
+		var recent = connection.violations[:0]
+		for _, violation := range connection.violations {
+			if now.Sub(violation) < options.ViolationWindow {
+				recent = append(recent, violation)
+			}
+		}
+		connection.violations = recent
+	}
+
+	connection.violations = append(connection.violations, now)
+
+	return false, len(connection.violations) >= options.MaxViolations
+}
+
+// Stop counting the connection for its IP address
+func (connection *Connection) Close() {
+	connection.limiter.mutex.Lock()
+	defer connection.limiter.mutex.Unlock()
+
+	limits := connection.limiter.ips[connection.ip]
+	limits.connections--
+	if limits.connections == 0 {
+		delete(connection.limiter.ips, connection.ip)
+	}
+}
+
+// Close the websocket connection of a client that keeps sending too many
+// messages with a policy violation close code
+func ClosePolicyViolation(ws *websocket.Conn, reason string) {
+	message := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason)
+	ws.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+	ws.Close()
+}
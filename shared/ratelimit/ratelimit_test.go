@@ -0,0 +1,187 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testClock is a clock the tests move by hand
+type testClock struct {
+	now time.Time
+}
+
+// Get the time of the clock
+func (clock *testClock) Now() time.Time {
+	return clock.now
+}
+
+// Move the clock forward
+func (clock *testClock) advance(duration time.Duration) {
+	clock.now = clock.now.Add(duration)
+}
+
+// Create a clock at a fixed time
+func newTestClock() *testClock {
+	return &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// Call Allow on the connection and fail if the result isn't the one wanted
+func checkAllow(t *testing.T, connection *Connection, wantAllowed bool, wantAbusive bool) {
+	t.Helper()
+
+	allowed, abusive := connection.Allow()
+	if allowed != wantAllowed || abusive != wantAbusive {
+		t.Fatalf("Allow() = %v, %v, want %v, %v", allowed, abusive, wantAllowed, wantAbusive)
+	}
+}
+
+func TestConnectionBurstAndRefill(t *testing.T) {
+	clock := newTestClock()
+	limiter := New(Options{MessageRate: 2, MessageBurst: 3}, clock.Now)
+	connection := limiter.Connect("10.0.0.1")
+
+	// The burst is allowed at once, then the rate
+	for i := 0; i < 3; i++ {
+		checkAllow(t, connection, true, false)
+	}
+	checkAllow(t, connection, false, false)
+
+	clock.advance(500 * time.Millisecond)
+	checkAllow(t, connection, true, false)
+	checkAllow(t, connection, false, false)
+
+	// The bucket never holds more than the burst
+	clock.advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		checkAllow(t, connection, true, false)
+	}
+	checkAllow(t, connection, false, false)
+}
+
+func TestConnectionNoLimit(t *testing.T) {
+	limiter := New(Options{}, newTestClock().Now)
+	connection := limiter.Connect("10.0.0.1")
+
+	for i := 0; i < 1000; i++ {
+		checkAllow(t, connection, true, false)
+	}
+}
+
+func TestSharedLimitDoesNotSpendConnectionToken(t *testing.T) {
+	clock := newTestClock()
+	limiter := New(Options{MessageRate: 0.1, MessageBurst: 1, IPMessageRate: 1, IPMessageBurst: 1}, clock.Now)
+	first := limiter.Connect("10.0.0.1")
+	second := limiter.Connect("10.0.0.1")
+
+	// The first connection takes the only token of the IP address
+	checkAllow(t, first, true, false)
+
+	// The second connection is over the limit of its IP address, not its own
+	checkAllow(t, second, false, false)
+
+	// Once the IP address has a token again the second connection still has
+	// its own, it would need 10s to earn it back if the denial had spent it
+	clock.advance(time.Second)
+	checkAllow(t, second, true, false)
+
+	// Over its own limit the token of the IP address isn't spent either, a
+	// new connection still gets it
+	clock.advance(time.Second)
+	checkAllow(t, second, false, false)
+	checkAllow(t, limiter.Connect("10.0.0.1"), true, false)
+}
+
+func TestViolationsWindow(t *testing.T) {
+	clock := newTestClock()
+	limiter := New(Options{MessageRate: 1, MessageBurst: 1, MaxViolations: 3, ViolationWindow: time.Minute}, clock.Now)
+	connection := limiter.Connect("10.0.0.1")
+
+	checkAllow(t, connection, true, false)
+	checkAllow(t, connection, false, false)
+	checkAllow(t, connection, false, false)
+
+	// The violations are forgotten once the window has passed
+	clock.advance(time.Minute)
+	checkAllow(t, connection, true, false)
+	checkAllow(t, connection, false, false)
+	checkAllow(t, connection, false, false)
+
+	// A third violation within the window closes the connection
+	clock.advance(30 * time.Second)
+	checkAllow(t, connection, true, false)
+	checkAllow(t, connection, false, true)
+}
+
+func TestViolationsWithoutWindow(t *testing.T) {
+	clock := newTestClock()
+	limiter := New(Options{MessageRate: 1, MessageBurst: 1, MaxViolations: 2}, clock.Now)
+	connection := limiter.Connect("10.0.0.1")
+
+	checkAllow(t, connection, true, false)
+	checkAllow(t, connection, false, false)
+
+	// Without a window the violations count for the whole connection
+	clock.advance(time.Hour)
+	checkAllow(t, connection, true, false)
+	checkAllow(t, connection, false, true)
+}
+
+func TestViolationsDisabled(t *testing.T) {
+	limiter := New(Options{MessageRate: 1, MessageBurst: 1, ViolationWindow: time.Minute}, newTestClock().Now)
+	connection := limiter.Connect("10.0.0.1")
+
+	checkAllow(t, connection, true, false)
+	for i := 0; i < 100; i++ {
+		checkAllow(t, connection, false, false)
+	}
+}
+
+func TestMaxConnectionsPerIP(t *testing.T) {
+	limiter := New(Options{MaxConnectionsPerIP: 2}, newTestClock().Now)
+
+	first := limiter.Connect("10.0.0.1")
+	second := limiter.Connect("10.0.0.1")
+	if first == nil || second == nil {
+		t.Fatal("refused a connection below the limit")
+	}
+
+	if limiter.Connect("10.0.0.1") != nil {
+		t.Fatal("accepted a connection over the limit")
+	}
+
+	// Another IP address has limits of its own
+	if limiter.Connect("10.0.0.2") == nil {
+		t.Fatal("refused the connection of another IP address")
+	}
+
+	// A closed connection frees its place and the last one forgets the IP address
+	first.Close()
+	third := limiter.Connect("10.0.0.1")
+	if third == nil {
+		t.Fatal("refused a connection after one was closed")
+	}
+
+	second.Close()
+	third.Close()
+	if _, ok := limiter.ips["10.0.0.1"]; ok {
+		t.Error("the IP address is still counted after its connections were closed")
+	}
+}
+
+func TestRemoteIP(t *testing.T) {
+	tests := map[string]string{
+		"10.0.0.1:1234":    "10.0.0.1",
+		"[::1]:8080":       "::1",
+		"10.0.0.1":         "10.0.0.1",
+		"unix-socket-peer": "unix-socket-peer",
+	}
+
+	for remoteAddr, want := range tests {
+		r := httptest.NewRequest("GET", "/ws", nil)
+		r.RemoteAddr = remoteAddr
+		if got := RemoteIP(r); got != want {
+			t.Errorf("RemoteIP(%q) = %q, want %q", remoteAddr, got, want)
+		}
+	}
+}
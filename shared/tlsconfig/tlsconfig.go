@@ -0,0 +1,97 @@
+// Package tlsconfig creates the TLS configs of the servers and the clients
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Create the TLS config of a server from the certificate and key files, a
+// client CA file requires every client to present a certificate signed by it.
+// nil is returned when no certificate is given so the server uses plain HTTP
+func NewServer(certFile string, keyFile string, clientCAFile string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		if clientCAFile != "" {
+			return nil, errors.New("a client CA needs a certificate and a key")
+		}
+		return nil, nil
+	}
+
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("TLS needs both a certificate and a key")
+	}
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var config = &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if clientCAFile != "" {
+		pool, err := loadCertPool(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
+
+// Create the TLS config used to dial wss:// urls, the CA file replaces the
+// system roots to verify the server, insecure skips verifying the server and
+// the certificate and key are presented to a server that requires mutual TLS
+func NewClient(caFile string, certFile string, keyFile string, insecure bool) (*tls.Config, error) {
+	var config = &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: insecure,
+	}
+
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = pool
+	}
+
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("a client certificate needs both a certificate and a key")
+	}
+
+	if certFile != "" {
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
+
+// Load the PEM certificates of a CA file into a pool
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var pool = x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificate found in %s", path)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,320 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"shared/auth"
+)
+
+// TestCertificates are the files of a CA and of a server and a client
+// certificate it signed
+type TestCertificates struct {
+	caFile         string
+	serverCertFile string
+	serverKeyFile  string
+	clientCertFile string
+	clientKeyFile  string
+}
+
+// A certificate and its key
+type testKeyPair struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+}
+
+// Generate a CA, a server certificate for 127.0.0.1 and a client certificate
+// for the common name "client" into a temporary directory
+func newTestCertificates(t *testing.T) *TestCertificates {
+	t.Helper()
+
+	dir := t.TempDir()
+	var certificates = &TestCertificates{
+		caFile:         filepath.Join(dir, "ca.pem"),
+		serverCertFile: filepath.Join(dir, "server.pem"),
+		serverKeyFile:  filepath.Join(dir, "server-key.pem"),
+		clientCertFile: filepath.Join(dir, "client.pem"),
+		clientKeyFile:  filepath.Join(dir, "client-key.pem"),
+	}
+
+	ca := newTestKeyPair(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "test CA"},
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}, nil)
+	writeTestKeyPair(t, ca, certificates.caFile, "")
+
+	server := newTestKeyPair(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "server"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+	writeTestKeyPair(t, server, certificates.serverCertFile, certificates.serverKeyFile)
+
+	client := newTestKeyPair(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "client"},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca)
+	writeTestKeyPair(t, client, certificates.clientCertFile, certificates.clientKeyFile)
+
+	return certificates
+}
+
+// Create a certificate from the template signed by the parent, it is self
+// signed when the parent is nil
+func newTestKeyPair(t *testing.T, template *x509.Certificate, parent *testKeyPair) *testKeyPair {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	var signer = &testKeyPair{certificate: template, key: key}
+	if parent != nil {
+		signer = parent
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer.certificate, &key.PublicKey, signer.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testKeyPair{certificate: certificate, key: key}
+}
+
+// Write the certificate and the key as PEM files, the key is skipped when
+// its path is empty
+func writeTestKeyPair(t *testing.T, pair *testKeyPair, certFile string, keyFile string) {
+	t.Helper()
+
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: pair.certificate.Raw})
+	if err := os.WriteFile(certFile, certificate, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if keyFile == "" {
+		return
+	}
+
+	der, err := x509.MarshalECPrivateKey(pair.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(keyFile, key, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Serve a websocket endpoint over TLS that authenticates the upgrade and
+// sends the method and the subjects of the identity as its only message
+func startTestTLSServer(t *testing.T, config *tls.Config, authenticator auth.Authenticator) *httptest.Server {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{}
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity := auth.AuthenticateUpgrade(authenticator, w, r)
+		if identity == nil {
+			return
+		}
+
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		ws.WriteMessage(websocket.TextMessage, []byte(identity.Method+" "+identity.Subject+" "+identity.Certificate))
+	}))
+
+	// Serve with the config under test instead of the httptest certificate
+	testServer.TLS = config
+	testServer.StartTLS()
+	t.Cleanup(testServer.Close)
+
+	return testServer
+}
+
+// Dial the wss:// url of the test server with the client TLS config and read
+// the identity the server sent
+func dialTestTLSServer(testServer *httptest.Server, config *tls.Config, header http.Header) (string, *http.Response, error) {
+	dialer := websocket.Dialer{TLSClientConfig: config, HandshakeTimeout: 5 * time.Second}
+
+	ws, response, err := dialer.Dial("wss"+strings.TrimPrefix(testServer.URL, "https"), header)
+	if err != nil {
+		return "", response, err
+	}
+	defer ws.Close()
+
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, message, err := ws.ReadMessage()
+
+	return string(message), response, err
+}
+
+func TestWSS(t *testing.T) {
+	certificates := newTestCertificates(t)
+
+	serverConfig, err := NewServer(certificates.serverCertFile, certificates.serverKeyFile, "")
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	testServer := startTestTLSServer(t, serverConfig, nil)
+
+	clientConfig, err := NewClient(certificates.caFile, "", "", false)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	identity, _, err := dialTestTLSServer(testServer, clientConfig, nil)
+	if err != nil {
+		t.Fatalf("dial with the CA: %v", err)
+	}
+
+	if identity != "none anonymous " {
+		t.Errorf("got identity %q, want an anonymous one", identity)
+	}
+
+	// The test CA isn't in the system roots
+	systemConfig, err := NewClient("", "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := dialTestTLSServer(testServer, systemConfig, nil); err == nil {
+		t.Error("dial without the CA verified the server")
+	}
+
+	insecureConfig, err := NewClient("", "", "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := dialTestTLSServer(testServer, insecureConfig, nil); err != nil {
+		t.Errorf("dial skipping the verification: %v", err)
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	certificates := newTestCertificates(t)
+
+	serverConfig, err := NewServer(certificates.serverCertFile, certificates.serverKeyFile, certificates.caFile)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	testServer := startTestTLSServer(t, serverConfig, nil)
+
+	clientConfig, err := NewClient(certificates.caFile, certificates.clientCertFile, certificates.clientKeyFile, false)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	identity, _, err := dialTestTLSServer(testServer, clientConfig, nil)
+	if err != nil {
+		t.Fatalf("dial with a client certificate: %v", err)
+	}
+
+	if identity != "mtls client client" {
+		t.Errorf("got identity %q, want the client certificate", identity)
+	}
+
+	// The handshake fails without a client certificate
+	noCertificateConfig, err := NewClient(certificates.caFile, "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := dialTestTLSServer(testServer, noCertificateConfig, nil); err == nil {
+		t.Error("dial without a client certificate was accepted")
+	}
+
+	// A certificate of the CA that isn't for client auth is rejected too
+	wrongCertificateConfig, err := NewClient(certificates.caFile, certificates.serverCertFile, certificates.serverKeyFile, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := dialTestTLSServer(testServer, wrongCertificateConfig, nil); err == nil {
+		t.Error("dial with a server certificate as the client certificate was accepted")
+	}
+}
+
+func TestMutualTLSWithToken(t *testing.T) {
+	certificates := newTestCertificates(t)
+
+	tokenFile := filepath.Join(t.TempDir(), "tokens")
+	if err := os.WriteFile(tokenFile, []byte("secret alice\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	authenticator, err := auth.NewAuthenticator(tokenFile, "", time.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverConfig, err := NewServer(certificates.serverCertFile, certificates.serverKeyFile, certificates.caFile)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	testServer := startTestTLSServer(t, serverConfig, authenticator)
+
+	clientConfig, err := NewClient(certificates.caFile, certificates.clientCertFile, certificates.clientKeyFile, false)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	// Both the token and the certificate are recorded
+	identity, _, err := dialTestTLSServer(testServer, clientConfig, http.Header{"Authorization": {"Bearer secret"}})
+	if err != nil {
+		t.Fatalf("dial with a token and a client certificate: %v", err)
+	}
+
+	if identity != "token+mtls alice client" {
+		t.Errorf("got identity %q, want the token subject and the certificate", identity)
+	}
+
+	// The certificate alone isn't enough when the server has an authenticator
+	_, response, err := dialTestTLSServer(testServer, clientConfig, nil)
+	if err == nil {
+		t.Fatal("dial with only a client certificate was accepted")
+	}
+
+	if response == nil || response.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got response %v, want a 401", response)
+	}
+}
@@ -0,0 +1,109 @@
+// Package tracing sets up the export of the query spans and carries their
+// trace context in the traceparent of a query
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// The exporters the spans can be written with, stdout and file write each
+// span as one JSON object per line
+const (
+	NoExporter     = "none"
+	StdoutExporter = "stdout"
+	FileExporter   = "file"
+)
+
+// The name of the tracer and the file the file exporter writes to by default
+const (
+	TracerName  = "pokemon"
+	DefaultFile = "traces.jsonl"
+)
+
+// The key of the trace context field
+const traceparentHeader = "traceparent"
+
+// The W3C trace context propagator that writes and reads the traceparent of
+// a query
+var traceContext = propagation.TraceContext{}
+
+// Query is a pokemon query of the client or the server protocol
+type Query interface {
+	GetId() string
+	GetName() string
+	GetRegion() string
+}
+
+// Make the global tracer provider export the spans of the service with the
+// exporter, the file exporter appends to the file at path. The returned
+// function flushes the spans that are still buffered and must be called
+// before exiting
+func Setup(service string, exporter string, path string) (func(), error) {
+	var w io.Writer
+	var file *os.File
+	switch exporter {
+	case NoExporter:
+		return func() {}, nil
+	case StdoutExporter:
+		w = os.Stdout
+	case FileExporter:
+		var err error
+		if file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+			return nil, err
+		}
+		w = file
+	default:
+		return nil, fmt.Errorf("invalid trace exporter %q, expected %s, %s or %s", exporter, NoExporter, StdoutExporter, FileExporter)
+	}
+
+	spanExporter, err := stdouttrace.New(stdouttrace.WithWriter(w))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", service))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func() {
+		provider.Shutdown(context.Background())
+		if file != nil {
+			file.Close()
+		}
+	}, nil
+}
+
+// Get the traceparent of the span in the context, empty if it has none
+func InjectTraceparent(ctx context.Context) string {
+	var carrier = propagation.MapCarrier{}
+	traceContext.Inject(ctx, carrier)
+
+	return carrier[traceparentHeader]
+}
+
+// Get a context with the remote span of the traceparent as its parent, an
+// empty or invalid traceparent leaves the context unchanged
+func ExtractTraceparent(ctx context.Context, traceparent string) context.Context {
+	return traceContext.Extract(ctx, propagation.MapCarrier{traceparentHeader: traceparent})
+}
+
+// Get the attributes of a query span
+func QueryAttributes(query Query) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("pokemon.query.id", query.GetId()),
+		attribute.String("pokemon.query.name", query.GetName()),
+		attribute.String("pokemon.query.region", query.GetRegion()),
+	}
+}
@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// testQuery is a query with the getters of the generated protocol types
+type testQuery struct {
+	id, name, region string
+}
+
+func (query testQuery) GetId() string     { return query.id }
+func (query testQuery) GetName() string   { return query.name }
+func (query testQuery) GetRegion() string { return query.region }
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	tracer := sdktrace.NewTracerProvider().Tracer("test")
+	ctx, span := tracer.Start(context.Background(), "query")
+
+	traceparent := InjectTraceparent(ctx)
+	if traceparent == "" {
+		t.Fatal("no traceparent injected for a recording span")
+	}
+
+	remote := trace.SpanContextFromContext(ExtractTraceparent(context.Background(), traceparent))
+	if remote.TraceID() != span.SpanContext().TraceID() || remote.SpanID() != span.SpanContext().SpanID() || !remote.IsRemote() {
+		t.Errorf("extracted %v from %q, want the remote span %v", remote, traceparent, span.SpanContext())
+	}
+
+	// A context without a span has no traceparent
+	if traceparent := InjectTraceparent(context.Background()); traceparent != "" {
+		t.Errorf("injected %q without a span", traceparent)
+	}
+
+	// An empty or invalid traceparent leaves the context without a span
+	for _, traceparent := range []string{"", "00-bad"} {
+		if extracted := trace.SpanContextFromContext(ExtractTraceparent(context.Background(), traceparent)); extracted.IsValid() {
+			t.Errorf("extracted %v from %q, want nothing", extracted, traceparent)
+		}
+	}
+}
+
+func TestQueryAttributes(t *testing.T) {
+	attributes := QueryAttributes(testQuery{id: "25", region: "Kanto"})
+
+	var values = make(map[string]string)
+	for _, attribute := range attributes {
+		values[string(attribute.Key)] = attribute.Value.AsString()
+	}
+
+	for key, want := range map[string]string{
+		"pokemon.query.id":     "25",
+		"pokemon.query.name":   "",
+		"pokemon.query.region": "Kanto",
+	} {
+		if got, ok := values[key]; !ok || got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestSetup(t *testing.T) {
+	if _, err := Setup("test", "jaeger", ""); err == nil {
+		t.Error("set up an unknown exporter")
+	}
+
+	if _, err := Setup("test", FileExporter, filepath.Join(t.TempDir(), "missing", DefaultFile)); err == nil {
+		t.Error("set up the file exporter in a missing directory")
+	}
+
+	shutdown, err := Setup("test", NoExporter, "")
+	if err != nil {
+		t.Fatalf("Setup(%s): %v", NoExporter, err)
+	}
+	shutdown()
+}